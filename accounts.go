@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Balance sheet classes (BClass) of assets and liabilities, only these
+// accounts have statements.
+var BALANCE_SHEET_CLASSES = []string{"1", "2"}
+
+// Account of the chart of accounts (Accounts table).
+type Account struct {
+	Number      string
+	Description string
+	// Group the account is summed up in.
+	Group string
+	// Currency code, empty for the base currency.
+	Currency string
+	// Balance sheet class: 1 assets, 2 liabilities, 3 expenses, 4 income.
+	Class string
+	// Opening balance in the base and in the account currency, the latter
+	// is only set for foreign currency accounts.
+	Opening         string
+	OpeningCurrency string
+	// Referenced by a booking but missing in the accounts table.
+	IsUnknown bool
+}
+
+func AccountsFromTable(table Table) map[string]Account {
+	rsl := map[string]Account{}
+	for _, row := range table.RowList {
+		// Group and total rows have no account number.
+		if row.Account == "" || row.Section == "*" {
+			continue
+		}
+		rsl[row.Account] = Account{
+			Number:          row.Account,
+			Description:     row.Description,
+			Group:           row.Gr,
+			Currency:        row.Currency,
+			Class:           row.BClass,
+			Opening:         row.Opening,
+			OpeningCurrency: row.OpeningCurrency,
+		}
+	}
+	return rsl
+}
+
+func (a Account) IsBalanceSheetAccount() bool {
+	return slices.Contains(BALANCE_SHEET_CLASSES, a.Class)
+}
+
+func (a Account) String() string {
+	if a.Description == "" {
+		return a.Number
+	}
+	return fmt.Sprint(a.Number, " ", a.Description)
+}
+
+// ResolveAccounts sets the accounts booked by the transactions of each
+// document and reports the accounts of all bookings of the journal missing
+// in the chart of accounts. The description of a missing account falls back
+// to the one of the booking.
+func (d *Dossier) ResolveAccounts(cashBasisAccounting bool) {
+	if len(d.Accounts) == 0 {
+		return
+	}
+	unknown := map[string][]string{}
+	for _, tx := range d.bookings {
+		for _, ref := range tx.accountRefs(cashBasisAccounting) {
+			if _, ok := d.Accounts[ref.Number]; ref.Number != "" && !ok {
+				unknown[ref.Number] = append(unknown[ref.Number], tx.Unique)
+			}
+		}
+	}
+	numbers := slices.Sorted(maps.Keys(unknown))
+	for _, number := range numbers {
+		fmt.Printf("account '%s' is not in the accounts table (bookings %s)\n", number, strings.Join(unknown[number], ", "))
+	}
+
+	for i := range d.JournalEntries {
+		doc := &d.JournalEntries[i]
+		doc.Accounts = nil
+		for _, tx := range doc.Transactions {
+			for _, ref := range tx.accountRefs(cashBasisAccounting) {
+				if ref.Number == "" || slices.ContainsFunc(doc.Accounts, func(a Account) bool {
+					return a.Number == ref.Number
+				}) {
+					continue
+				}
+				account, ok := d.Accounts[ref.Number]
+				if !ok {
+					account = ref
+					account.IsUnknown = true
+				}
+				doc.Accounts = append(doc.Accounts, account)
+			}
+		}
+	}
+}
+
+// Accounts referenced by the transaction with the descriptions of the
+// journal.
+func (t Transaction) accountRefs(cashBasisAccounting bool) []Account {
+	if cashBasisAccounting {
+		return []Account{{Number: t.Account, Description: t.AccountDes}}
+	}
+	return []Account{
+		{Number: t.AccountDebit, Description: t.AccountDebitDes},
+		{Number: t.AccountCredit, Description: t.AccountCreditDes},
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Category of a cash basis (EÜR) file (Categories table).
+type Category struct {
+	Code        string
+	Description string
+	// Group the category is summed up in.
+	Group string
+	// Referenced by a booking but missing in the categories table.
+	IsUnknown bool
+}
+
+func (c Category) String() string {
+	if c.Description == "" {
+		return c.Code
+	}
+	return fmt.Sprint(c.Code, " ", c.Description)
+}
+
+// Returns the categories by code and the descriptions of the groups by
+// group code.
+func CategoriesFromTable(table Table) (map[string]Category, map[string]string) {
+	categories := map[string]Category{}
+	groups := map[string]string{}
+	for _, row := range table.RowList {
+		if row.Section == "*" {
+			continue
+		}
+		if row.Category != "" {
+			categories[row.Category] = Category{
+				Code:        row.Category,
+				Description: row.Description,
+				Group:       row.Gr,
+			}
+		} else if row.Group != "" {
+			groups[row.Group] = row.Description
+		}
+	}
+	return categories, groups
+}
+
+// ResolveCategories sets the categories booked by the transactions of each
+// document and reports the categories missing in the categories table. Only
+// used for cash basis accounting.
+func (d *Dossier) ResolveCategories() {
+	if len(d.Categories) == 0 {
+		return
+	}
+	for i := range d.JournalEntries {
+		doc := &d.JournalEntries[i]
+		doc.Categories = nil
+		for _, tx := range doc.Transactions {
+			if tx.Category == "" || slices.ContainsFunc(doc.Categories, func(c Category) bool {
+				return c.Code == tx.Category
+			}) {
+				continue
+			}
+			category, ok := d.Categories[tx.Category]
+			if !ok {
+				fmt.Printf("%s: category '%s' of booking %s is not in the categories table\n", doc.Path, tx.Category, tx.Unique)
+				category = Category{Code: tx.Category, Description: tx.CategoryDes, IsUnknown: true}
+			}
+			doc.Categories = append(doc.Categories, category)
+		}
+	}
+}
+
+// Income or expenses of a cash basis summary.
+type CashBasisSection struct {
+	Groups []CashBasisGroup
+	Total  float64
+}
+
+// Categories of one group of the categories table.
+type CashBasisGroup struct {
+	Code        string
+	Description string
+	Categories  []CashBasisCategory
+	Total       float64
+}
+
+type CashBasisCategory struct {
+	Category
+	Total float64
+	// Number of bookings.
+	Count int
+}
+
+// Income and expenses of all bookings by group and category, structured
+// like the Anlage EÜR (Betriebseinnahmen, Betriebsausgaben, Gewinn).
+// Bookings with an amount but without category show up as such, AP/AR
+// auxiliary bookings are left out.
+type CashBasisSummary struct {
+	Income   CashBasisSection
+	Expenses CashBasisSection
+}
+
+func (s CashBasisSummary) Profit() float64 {
+	return s.Income.Total - s.Expenses.Total
+}
+
+// Group code of bookings without category.
+const UNCATEGORIZED_GROUP = "?"
+
+func (d Dossier) CashBasisSummary() (CashBasisSummary, error) {
+	rsl := CashBasisSummary{}
+	for _, tx := range d.bookings {
+		if tx.IsAPARAuxiliary(true) {
+			continue
+		}
+		for _, entry := range []struct {
+			amount  string
+			section *CashBasisSection
+		}{{tx.Income, &rsl.Income}, {tx.Expenses, &rsl.Expenses}} {
+			if strings.TrimSpace(entry.amount) == "" {
+				continue
+			}
+			amount, err := tx.parseAmount(entry.amount)
+			if err != nil {
+				return rsl, err
+			}
+			d.addToSection(entry.section, tx, amount)
+		}
+	}
+	for _, section := range []*CashBasisSection{&rsl.Income, &rsl.Expenses} {
+		slices.SortFunc(section.Groups, func(a, b CashBasisGroup) int {
+			// Uncategorized bookings come last.
+			if (a.Code == UNCATEGORIZED_GROUP) != (b.Code == UNCATEGORIZED_GROUP) {
+				if a.Code == UNCATEGORIZED_GROUP {
+					return 1
+				}
+				return -1
+			}
+			return strings.Compare(a.Code, b.Code)
+		})
+		for i := range section.Groups {
+			slices.SortFunc(section.Groups[i].Categories, func(a, b CashBasisCategory) int {
+				return strings.Compare(a.Code, b.Code)
+			})
+		}
+	}
+	return rsl, nil
+}
+
+func (d Dossier) addToSection(section *CashBasisSection, tx Transaction, amount float64) {
+	category, ok := d.Categories[tx.Category]
+	if !ok {
+		category = Category{Code: tx.Category, Description: tx.CategoryDes, IsUnknown: tx.Category != ""}
+	}
+	groupCode := category.Group
+	description := d.CategoryGroups[groupCode]
+	if tx.Category == "" {
+		groupCode, description = UNCATEGORIZED_GROUP, "Ohne Kategorie"
+	}
+	i := slices.IndexFunc(section.Groups, func(g CashBasisGroup) bool {
+		return g.Code == groupCode
+	})
+	if i == -1 {
+		section.Groups = append(section.Groups, CashBasisGroup{Code: groupCode, Description: description})
+		i = len(section.Groups) - 1
+	}
+	group := &section.Groups[i]
+	j := slices.IndexFunc(group.Categories, func(c CashBasisCategory) bool {
+		return c.Code == category.Code
+	})
+	if j == -1 {
+		group.Categories = append(group.Categories, CashBasisCategory{Category: category})
+		j = len(group.Categories) - 1
+	}
+	group.Categories[j].Total += amount
+	group.Categories[j].Count++
+	group.Total += amount
+	section.Total += amount
+}
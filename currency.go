@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ISO 4217 currency.
+type Currency struct {
+	Code   string
+	Symbol string
+	// Number of decimal places (minor unit).
+	Decimals int
+}
+
+// Currencies with a symbol other than the code or without two decimal
+// places, all others are shown by their code with two decimal places.
+var CURRENCIES = map[string]Currency{
+	"AUD": {"AUD", "A$", 2},
+	"BHD": {"BHD", "BHD", 3},
+	"BRL": {"BRL", "R$", 2},
+	"CAD": {"CAD", "C$", 2},
+	"CLP": {"CLP", "CLP", 0},
+	"CNY": {"CNY", "¥", 2},
+	"EUR": {"EUR", "€", 2},
+	"GBP": {"GBP", "£", 2},
+	"HKD": {"HKD", "HK$", 2},
+	"INR": {"INR", "₹", 2},
+	"ISK": {"ISK", "ISK", 0},
+	"JOD": {"JOD", "JOD", 3},
+	"JPY": {"JPY", "¥", 0},
+	"KRW": {"KRW", "₩", 0},
+	"KWD": {"KWD", "KWD", 3},
+	"NZD": {"NZD", "NZ$", 2},
+	"OMR": {"OMR", "OMR", 3},
+	"TND": {"TND", "TND", 3},
+	"USD": {"USD", "$", 2},
+	"VND": {"VND", "₫", 0},
+}
+
+// LookupCurrency returns the currency with the given ISO 4217 code.
+func LookupCurrency(code string) Currency {
+	code = normalizeCurrencyCode(code)
+	if rsl, ok := CURRENCIES[code]; ok {
+		return rsl
+	}
+	return Currency{Code: code, Symbol: code, Decimals: 2}
+}
+
+// Currency code as ISO 4217 code, the exports don't enforce the case.
+func normalizeCurrencyCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Amount with the decimal places of the currency, without symbol.
+func (c Currency) FmtValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', c.Decimals, 64)
+}
+
+// Amount with the decimal places and the symbol of the currency.
+func (c Currency) Fmt(value float64) string {
+	return fmt.Sprint(c.FmtValue(value), " ", c.Symbol)
+}
+
+// Total of bookings in one currency.
+type CurrencyTotal struct {
+	Currency Currency
+	// In the currency of the bookings and in base currency.
+	Amount float64
+	Base   float64
+}
+
+// CurrencyTotals sums the amounts of the transactions by the currency of
+// the booking, the base currency comes first.
+func (t Transactions) CurrencyTotals(base Currency, cashBasisAccounting bool) ([]CurrencyTotal, error) {
+	rsl := []CurrencyTotal{{Currency: base}}
+	for _, tx := range t {
+		if tx.IsAPARAuxiliary(cashBasisAccounting) {
+			continue
+		}
+		baseAmount, err := tx.BaseAmount(cashBasisAccounting)
+		if err != nil {
+			return nil, err
+		}
+		amount := baseAmount
+		code := tx.CurrencyCode(base)
+		if code != base.Code {
+			if amount, err = tx.CurrencyAmount(); err != nil {
+				return nil, err
+			}
+			// Cash basis files have no sign on the currency amount.
+			if cashBasisAccounting && (baseAmount < 0) != (amount < 0) {
+				amount = -amount
+			}
+		}
+		i := 0
+		for i < len(rsl) && rsl[i].Currency.Code != code {
+			i++
+		}
+		if i == len(rsl) {
+			rsl = append(rsl, CurrencyTotal{Currency: LookupCurrency(code)})
+		}
+		rsl[i].Amount += amount
+		rsl[i].Base += baseAmount
+	}
+	return rsl, nil
+}
+
+// Whether one of the transactions is booked in another than the base
+// currency.
+func (t Transactions) HasForeignCurrency(base Currency) bool {
+	for _, tx := range t {
+		if tx.IsForeignCurrency(base) {
+			return true
+		}
+	}
+	return false
+}
+
+// Code of the currency of the booking, the base currency if it has none.
+func (t Transaction) CurrencyCode(base Currency) string {
+	if code := normalizeCurrencyCode(t.ExchangeCurrency); code != "" {
+		return code
+	}
+	return base.Code
+}
+
+// Whether the booking is in another than the base currency.
+func (t Transaction) IsForeignCurrency(base Currency) bool {
+	return t.CurrencyCode(base) != base.Code
+}
+
+// Booked exchange rate for one unit of the currency with the given number
+// of decimal places, the rate as booked if it can't be parsed.
+func (t Transaction) FmtExchangeRate(decimals int) string {
+	rate, err := t.exchangeRateValue()
+	if err != nil {
+		return t.ExchangeRate
+	}
+	return strconv.FormatFloat(rate, 'f', decimals, 64)
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+// File names under which Factur-X, ZUGFeRD and XRechnung embed the XML
+// invoice into the PDF (lower case).
+var EINVOICE_ATTACHMENT_NAMES = []string{
+	"factur-x.xml",
+	"zugferd-invoice.xml",
+	"zugferd_invoice.xml",
+	"xrechnung.xml",
+}
+
+// Structured invoice data of an e-invoice. Amounts are kept as in the XML.
+type EInvoice struct {
+	Syntax     string // CII or UBL
+	Number     string
+	IssueDate  time.Time
+	Seller     string
+	Buyer      string
+	Currency   string
+	NetTotal   string
+	TaxTotal   string
+	GrossTotal string
+	DueAmount  string
+	Lines      []EInvoiceLine
+}
+
+type EInvoiceLine struct {
+	Name      string
+	Quantity  string
+	Unit      string
+	NetAmount string
+}
+
+func (e EInvoice) FmtIssueDate() string {
+	if e.IssueDate.Equal(time.Time{}) {
+		return UNKNOWN_STR
+	}
+	return e.IssueDate.Format(DATE_FORMAT)
+}
+
+func (e EInvoice) String() string {
+	return fmt.Sprintf(
+		"%s · Nr. %s · %s · Netto %s, MwSt. %s, Brutto %s %s",
+		e.Seller, e.Number, e.FmtIssueDate(), e.NetTotal, e.TaxTotal, e.GrossTotal, e.Currency,
+	)
+}
+
+// ParseEInvoice parses an invoice in the UN/CEFACT Cross Industry Invoice
+// syntax (Factur-X, ZUGFeRD, XRechnung CII) or in the UBL syntax
+// (XRechnung UBL, Peppol BIS).
+func ParseEInvoice(data []byte) (*EInvoice, error) {
+	root, err := xmlRootName(data)
+	if err != nil {
+		return nil, err
+	}
+	switch root {
+	case "CrossIndustryInvoice":
+		return parseCIIInvoice(data)
+	case "Invoice", "CreditNote":
+		return parseUBLInvoice(data)
+	default:
+		return nil, fmt.Errorf("unknown e-invoice root element '%s'", root)
+	}
+}
+
+// Returns the e-invoice embedded into the PDF at the given path, nil if
+// there is none.
+func EInvoiceFromPDF(path string) (*EInvoice, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	attachments, err := api.Attachments(file, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	for _, attachment := range attachments {
+		if !isEInvoiceAttachmentName(attachment.FileName) {
+			continue
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		extracted, err := api.ExtractAttachmentsRaw(file, "", []string{attachment.FileName}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", attachment.FileName, err)
+		}
+		if len(extracted) == 0 {
+			return nil, fmt.Errorf("failed to extract %s", attachment.FileName)
+		}
+		data, err := io.ReadAll(extracted[0])
+		if err != nil {
+			return nil, err
+		}
+		return ParseEInvoice(data)
+	}
+	return nil, nil
+}
+
+func isEInvoiceAttachmentName(name string) bool {
+	name = strings.ToLower(filepath.Base(name))
+	for _, candidate := range EINVOICE_ATTACHMENT_NAMES {
+		if name == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+func xmlRootName(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("failed to find XML root element: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+type xmlAmount struct {
+	Value      string `xml:",chardata"`
+	CurrencyID string `xml:"currencyID,attr"`
+}
+
+type xmlQuantity struct {
+	Value    string `xml:",chardata"`
+	UnitCode string `xml:"unitCode,attr"`
+}
+
+type ciiInvoice struct {
+	Number      string `xml:"ExchangedDocument>ID"`
+	IssueDate   string `xml:"ExchangedDocument>IssueDateTime>DateTimeString"`
+	Transaction struct {
+		Lines []struct {
+			Name      string      `xml:"SpecifiedTradeProduct>Name"`
+			Quantity  xmlQuantity `xml:"SpecifiedLineTradeDelivery>BilledQuantity"`
+			NetAmount string      `xml:"SpecifiedLineTradeSettlement>SpecifiedTradeSettlementLineMonetarySummation>LineTotalAmount"`
+		} `xml:"IncludedSupplyChainTradeLineItem"`
+		Seller     string `xml:"ApplicableHeaderTradeAgreement>SellerTradeParty>Name"`
+		Buyer      string `xml:"ApplicableHeaderTradeAgreement>BuyerTradeParty>Name"`
+		Settlement struct {
+			Currency  string `xml:"InvoiceCurrencyCode"`
+			Summation struct {
+				NetTotal   string      `xml:"TaxBasisTotalAmount"`
+				TaxTotals  []xmlAmount `xml:"TaxTotalAmount"`
+				GrossTotal string      `xml:"GrandTotalAmount"`
+				DueAmount  string      `xml:"DuePayableAmount"`
+			} `xml:"SpecifiedTradeSettlementHeaderMonetarySummation"`
+		} `xml:"ApplicableHeaderTradeSettlement"`
+	} `xml:"SupplyChainTradeTransaction"`
+}
+
+func parseCIIInvoice(data []byte) (*EInvoice, error) {
+	var raw ciiInvoice
+	if err := xml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	settlement := raw.Transaction.Settlement
+	rsl := &EInvoice{
+		Syntax:     "CII",
+		Number:     strings.TrimSpace(raw.Number),
+		Seller:     strings.TrimSpace(raw.Transaction.Seller),
+		Buyer:      strings.TrimSpace(raw.Transaction.Buyer),
+		Currency:   strings.TrimSpace(settlement.Currency),
+		NetTotal:   strings.TrimSpace(settlement.Summation.NetTotal),
+		GrossTotal: strings.TrimSpace(settlement.Summation.GrossTotal),
+		DueAmount:  strings.TrimSpace(settlement.Summation.DueAmount),
+	}
+	// The tax total may be given a second time in the tax currency.
+	for _, tax := range settlement.Summation.TaxTotals {
+		if rsl.TaxTotal == "" || tax.CurrencyID == rsl.Currency {
+			rsl.TaxTotal = strings.TrimSpace(tax.Value)
+		}
+	}
+	// Format 102 of UN/CEFACT: YYYYMMDD.
+	rsl.IssueDate, _ = time.Parse("20060102", strings.TrimSpace(raw.IssueDate))
+	for _, line := range raw.Transaction.Lines {
+		rsl.Lines = append(rsl.Lines, EInvoiceLine{
+			Name:      strings.TrimSpace(line.Name),
+			Quantity:  strings.TrimSpace(line.Quantity.Value),
+			Unit:      line.Quantity.UnitCode,
+			NetAmount: strings.TrimSpace(line.NetAmount),
+		})
+	}
+	return rsl, nil
+}
+
+type ublParty struct {
+	Name             string `xml:"Party>PartyName>Name"`
+	RegistrationName string `xml:"Party>PartyLegalEntity>RegistrationName"`
+}
+
+func (p ublParty) name() string {
+	if p.RegistrationName != "" {
+		return strings.TrimSpace(p.RegistrationName)
+	}
+	return strings.TrimSpace(p.Name)
+}
+
+type ublLine struct {
+	Name             string      `xml:"Item>Name"`
+	InvoicedQuantity xmlQuantity `xml:"InvoicedQuantity"`
+	CreditedQuantity xmlQuantity `xml:"CreditedQuantity"`
+	NetAmount        string      `xml:"LineExtensionAmount"`
+}
+
+type ublInvoice struct {
+	Number     string    `xml:"ID"`
+	IssueDate  string    `xml:"IssueDate"`
+	Currency   string    `xml:"DocumentCurrencyCode"`
+	Seller     ublParty  `xml:"AccountingSupplierParty"`
+	Buyer      ublParty  `xml:"AccountingCustomerParty"`
+	TaxTotals  []string  `xml:"TaxTotal>TaxAmount"`
+	NetTotal   string    `xml:"LegalMonetaryTotal>TaxExclusiveAmount"`
+	GrossTotal string    `xml:"LegalMonetaryTotal>TaxInclusiveAmount"`
+	DueAmount  string    `xml:"LegalMonetaryTotal>PayableAmount"`
+	Lines      []ublLine `xml:"InvoiceLine"`
+	CreditNote []ublLine `xml:"CreditNoteLine"`
+}
+
+func parseUBLInvoice(data []byte) (*EInvoice, error) {
+	var raw ublInvoice
+	if err := xml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	rsl := &EInvoice{
+		Syntax:     "UBL",
+		Number:     strings.TrimSpace(raw.Number),
+		Seller:     raw.Seller.name(),
+		Buyer:      raw.Buyer.name(),
+		Currency:   strings.TrimSpace(raw.Currency),
+		NetTotal:   strings.TrimSpace(raw.NetTotal),
+		GrossTotal: strings.TrimSpace(raw.GrossTotal),
+		DueAmount:  strings.TrimSpace(raw.DueAmount),
+	}
+	if len(raw.TaxTotals) != 0 {
+		rsl.TaxTotal = strings.TrimSpace(raw.TaxTotals[0])
+	}
+	rsl.IssueDate, _ = time.Parse("2006-01-02", strings.TrimSpace(raw.IssueDate))
+	for _, line := range append(raw.Lines, raw.CreditNote...) {
+		quantity := line.InvoicedQuantity
+		if quantity.Value == "" {
+			quantity = line.CreditedQuantity
+		}
+		rsl.Lines = append(rsl.Lines, EInvoiceLine{
+			Name:      strings.TrimSpace(line.Name),
+			Quantity:  strings.TrimSpace(quantity.Value),
+			Unit:      quantity.UnitCode,
+			NetAmount: strings.TrimSpace(line.NetAmount),
+		})
+	}
+	return rsl, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate of a currency of the exchange rates table (ExchangeRates) of a
+// multi-currency file.
+type ExchangeRate struct {
+	// ISO 4217 code of the currency and of the currency the rate refers to,
+	// the latter is empty for the base currency.
+	Currency  string
+	Reference string
+	// Rates without date apply to all dates without a historic rate.
+	Date       time.Time
+	Rate       float64
+	Multiplier float64
+}
+
+type ExchangeRates []ExchangeRate
+
+// ExchangeRatesFromTable reads the rates of the table, rows without currency
+// or with an invalid rate are skipped. Historic rates are sorted by date.
+func ExchangeRatesFromTable(table Table) ExchangeRates {
+	rsl := ExchangeRates{}
+	for _, row := range table.RowList {
+		if row.Currency == "" || row.Section == "*" {
+			continue
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(row.Rate), 64)
+		if err != nil || rate == 0 {
+			continue
+		}
+		multiplier, err := parseMultiplier(row.Multiplier)
+		if err != nil {
+			continue
+		}
+		entry := ExchangeRate{
+			Currency:   normalizeCurrencyCode(row.Currency),
+			Reference:  normalizeCurrencyCode(row.CurrencyReference),
+			Rate:       rate,
+			Multiplier: multiplier,
+		}
+		if row.Date != "" {
+			date, err := time.Parse("2006-01-02", row.Date)
+			if err != nil {
+				continue
+			}
+			entry.Date = date
+		}
+		rsl = append(rsl, entry)
+	}
+	slices.SortStableFunc(rsl, func(a, b ExchangeRate) int {
+		return a.Date.Compare(b.Date)
+	})
+	return rsl
+}
+
+// Multiplier of a rate, 1 if empty.
+func parseMultiplier(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 1, nil
+	}
+	rsl, err := strconv.ParseFloat(value, 64)
+	if err == nil && rsl == 0 {
+		return 0, fmt.Errorf("multiplier must not be zero")
+	}
+	return rsl, err
+}
+
+// normalizeRate returns the value of one unit of the currency, a negative
+// multiplier stands for an inverted rate.
+func normalizeRate(rate, multiplier float64) float64 {
+	if multiplier < 0 {
+		return -multiplier / rate
+	}
+	return rate / multiplier
+}
+
+func (r ExchangeRate) Value() float64 {
+	return normalizeRate(r.Rate, r.Multiplier)
+}
+
+// Value of one unit of the currency with the given number of decimal
+// places, comparable with Transaction.FmtExchangeRate.
+func (r ExchangeRate) Fmt(decimals int) string {
+	return strconv.FormatFloat(r.Value(), 'f', decimals, 64)
+}
+
+// Lookup returns the rate of the currency on the given date: the most
+// recent historic rate up to the date or, if there is none, the rate
+// without date.
+func (e ExchangeRates) Lookup(currency, base string, date time.Time) (ExchangeRate, bool) {
+	rsl, found := ExchangeRate{}, false
+	for _, rate := range e {
+		if rate.Currency != currency || (rate.Reference != "" && rate.Reference != base) {
+			continue
+		}
+		if rate.Date.After(date) {
+			break
+		}
+		if !found || !rate.Date.IsZero() {
+			rsl, found = rate, true
+		}
+	}
+	return rsl, found
+}
+
+// CompareExchangeRates sets the reference rate of the exchange rates table
+// of all foreign currency bookings and reports the bookings whose rate
+// deviates more than maxDeviation percent from it.
+func (d *Dossier) CompareExchangeRates(maxDeviation float64) {
+	if len(d.ExchangeRates) == 0 {
+		return
+	}
+	base := d.Currency()
+	for i := range d.JournalEntries {
+		doc := &d.JournalEntries[i]
+		for j := range doc.Transactions {
+			tx := &doc.Transactions[j]
+			if !tx.IsForeignCurrency(base) {
+				continue
+			}
+			date, err := tx.ParsedDate()
+			if err != nil {
+				continue
+			}
+			reference, ok := d.ExchangeRates.Lookup(tx.CurrencyCode(base), base.Code, date)
+			if !ok {
+				fmt.Printf("%s: no rate for %s of booking %s in the exchange rates table\n", doc.Path, tx.ExchangeCurrency, tx.Unique)
+				continue
+			}
+			tx.ReferenceRate = &reference
+			booked, err := tx.exchangeRateValue()
+			if err != nil {
+				fmt.Printf("%s: %s\n", doc.Path, err)
+				continue
+			}
+			tx.RateDeviation = math.Abs(booked-reference.Value()) / reference.Value() * 100
+			if tx.RateDeviation > maxDeviation {
+				tx.IsRateDeviating = true
+				fmt.Printf(
+					"%s: exchange rate %s of booking %s deviates %.2f%% from the rate %s of the exchange rates table\n",
+					doc.Path, tx.FmtExchangeRate(6), tx.Unique, tx.RateDeviation, reference.Fmt(6),
+				)
+			}
+		}
+	}
+}
+
+// Documents with bookings whose exchange rate deviates from the exchange
+// rates table.
+func (d Dossier) RateDeviations() Documents {
+	rsl := Documents{}
+	for _, doc := range d.JournalEntries {
+		if slices.ContainsFunc(doc.Transactions, func(tx Transaction) bool {
+			return tx.IsRateDeviating
+		}) {
+			rsl = append(rsl, doc)
+		}
+	}
+	return rsl
+}
+
+// Booked exchange rate for one unit of the currency.
+func (t Transaction) exchangeRateValue() (float64, error) {
+	rate, err := strconv.ParseFloat(strings.TrimSpace(t.ExchangeRate), 64)
+	if err != nil || rate == 0 {
+		return 0, fmt.Errorf("invalid exchange rate '%s' of booking %s", t.ExchangeRate, t.Unique)
+	}
+	multiplier, err := parseMultiplier(t.ExchangeMultiplier)
+	if err != nil {
+		return 0, fmt.Errorf("invalid exchange multiplier '%s' of booking %s", t.ExchangeMultiplier, t.Unique)
+	}
+	return normalizeRate(rate, multiplier), nil
+}
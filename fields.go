@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Data types of the columns (Field.Datatype), all others are read as text.
+const (
+	DATATYPE_TEXT   = "Text"
+	DATATYPE_NUMBER = "Number"
+	DATATYPE_AMOUNT = "Amount"
+	DATATYPE_DATE   = "Date"
+	DATATYPE_TIME   = "Time"
+	DATATYPE_BOOL   = "Bool"
+)
+
+func (f Field) IsNumeric() bool {
+	return f.Datatype == DATATYPE_NUMBER || f.Datatype == DATATYPE_AMOUNT
+}
+
+// Parse returns the value of a cell of the column typed by the data type:
+// float64 for numbers and amounts, time.Time for dates and times, bool for
+// booleans and string for everything else. Dates are accepted in the format
+// of the export (2006-01-02) and of the report (02.01.2006).
+func (f Field) Parse(raw string) (any, error) {
+	raw = strings.TrimSpace(raw)
+	switch f.Datatype {
+	case DATATYPE_NUMBER, DATATYPE_AMOUNT:
+		rsl, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s '%s' in column %s", strings.ToLower(f.Datatype), raw, f.ID)
+		}
+		return rsl, nil
+	case DATATYPE_DATE:
+		for _, layout := range []string{"2006-01-02", DATE_FORMAT} {
+			if rsl, err := time.Parse(layout, raw); err == nil {
+				return rsl, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid date '%s' in column %s", raw, f.ID)
+	case DATATYPE_TIME:
+		for _, layout := range []string{TIME_FORMAT, "15:04"} {
+			if rsl, err := time.Parse(layout, raw); err == nil {
+				return rsl, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid time '%s' in column %s", raw, f.ID)
+	case DATATYPE_BOOL:
+		// Banana stores checked boxes as 1 and unchecked ones as empty.
+		switch strings.ToLower(raw) {
+		case "", "0", "false":
+			return false, nil
+		}
+		return true, nil
+	}
+	return raw, nil
+}
+
+// Header of the column, the field ID for exports without header.
+func (f Field) Title() string {
+	if f.Header1 != "" {
+		return f.Header1
+	}
+	return f.ID
+}
+
+// Fmt returns the value of a cell as shown in the report, dates in the date
+// format of the report and checked boxes as "ja". Values which can't be
+// parsed are shown as they are.
+func (f Field) Fmt(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	value, err := f.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	switch value := value.(type) {
+	case time.Time:
+		if f.Datatype == DATATYPE_TIME {
+			return value.Format(TIME_FORMAT)
+		}
+		return value.Format(DATE_FORMAT)
+	case bool:
+		if value {
+			return "ja"
+		}
+		return ""
+	}
+	return raw
+}
+
+// Maximum number of extra columns in the transaction table, each one takes
+// space from the description.
+const MAX_EXTRA_COLUMNS = 3
+
+// Journal column with the notes of the bookings.
+const NOTES_FIELD = "Notes"
+
+// ExtraColumns returns the journal columns with the given field IDs to be
+// shown in the transaction table.
+func (d Dossier) ExtraColumns(ids []string) ([]Field, error) {
+	if len(ids) > MAX_EXTRA_COLUMNS {
+		return nil, fmt.Errorf("at most %d extra columns can be shown, got %d", MAX_EXTRA_COLUMNS, len(ids))
+	}
+	rsl := []Field{}
+	for _, id := range ids {
+		field, err := journalField(id, d.JournalFields)
+		if err != nil {
+			return nil, fmt.Errorf("invalid column: %w", err)
+		}
+		rsl = append(rsl, field)
+	}
+	return rsl, nil
+}
+
+// Alignment of the column in the transaction table.
+func (f Field) align() string {
+	if f.IsNumeric() {
+		return "R"
+	}
+	return "L"
+}
@@ -0,0 +1,145 @@
+package main
+
+// Banana file type groups (FileTypeGroup of the file info).
+const (
+	FILE_TYPE_DOUBLE_ENTRY   = "100"
+	FILE_TYPE_INCOME_EXPENSE = "110"
+	FILE_TYPE_CASH_BOOK      = "130"
+)
+
+// Banana file type numbers (FileTypeNumber of the file info), multi-currency
+// only exists for double-entry files.
+const (
+	FILE_TYPE_NO_VAT             = "100"
+	FILE_TYPE_VAT                = "110"
+	FILE_TYPE_MULTI_CURRENCY     = "120"
+	FILE_TYPE_MULTI_CURRENCY_VAT = "130"
+)
+
+// Type of the accounting file, decides how the columns of the journal are
+// interpreted and shown:
+//
+//   - Double-entry: debit and credit account (AccountDebit, AccountCredit)
+//     and the amount in base currency (Amount), multi-currency files also
+//     the amount in the currency of the booking (AmountCurrency).
+//   - Income & expense (EÜR): one account (Account), the category
+//     (Category) and the amount either as Income or Expenses.
+//   - Cash book: like income & expense, but all bookings are on the one
+//     account of the file so there is no account column.
+//
+// VAT files additionally have the VAT code of each booking (VatCode).
+type FileType struct {
+	Group  string
+	Number string
+}
+
+// FileTypeFromTables reads the file type from the file info. Exports without
+// file type are detected by the columns of the journal.
+func FileTypeFromTables(fileInfo, journal Table) FileType {
+	group, _ := fileInfo.ValueById("FileTypeGroup")
+	number, _ := fileInfo.ValueById("FileTypeNumber")
+	rsl := FileType{Group: group, Number: number}
+	switch group {
+	case FILE_TYPE_DOUBLE_ENTRY, FILE_TYPE_INCOME_EXPENSE, FILE_TYPE_CASH_BOOK:
+		if number != "" {
+			return rsl
+		}
+	default:
+		rsl.Group = FILE_TYPE_DOUBLE_ENTRY
+		if journal.IsCashBasis() {
+			rsl.Group = FILE_TYPE_INCOME_EXPENSE
+			if len(journal.FieldList) != 0 && !journal.HasField("Account") {
+				rsl.Group = FILE_TYPE_CASH_BOOK
+			}
+		}
+	}
+	isMultiCurrency := rsl.Group == FILE_TYPE_DOUBLE_ENTRY && journal.HasField("AmountCurrency")
+	switch {
+	case isMultiCurrency && journal.HasField("VatCode"):
+		rsl.Number = FILE_TYPE_MULTI_CURRENCY_VAT
+	case isMultiCurrency:
+		rsl.Number = FILE_TYPE_MULTI_CURRENCY
+	case journal.HasField("VatCode"):
+		rsl.Number = FILE_TYPE_VAT
+	default:
+		rsl.Number = FILE_TYPE_NO_VAT
+	}
+	return rsl
+}
+
+func (f FileType) IsCashBasis() bool {
+	return f.Group == FILE_TYPE_INCOME_EXPENSE || f.Group == FILE_TYPE_CASH_BOOK
+}
+
+func (f FileType) IsCashBook() bool {
+	return f.Group == FILE_TYPE_CASH_BOOK
+}
+
+func (f FileType) HasVAT() bool {
+	return f.Number == FILE_TYPE_VAT || f.Number == FILE_TYPE_MULTI_CURRENCY_VAT
+}
+
+func (f FileType) IsMultiCurrency() bool {
+	return f.Group == FILE_TYPE_DOUBLE_ENTRY &&
+		(f.Number == FILE_TYPE_MULTI_CURRENCY || f.Number == FILE_TYPE_MULTI_CURRENCY_VAT)
+}
+
+func (f FileType) String() string {
+	rsl := "Double-entry accounting"
+	switch f.Group {
+	case FILE_TYPE_INCOME_EXPENSE:
+		rsl = "Income & expense accounting"
+	case FILE_TYPE_CASH_BOOK:
+		rsl = "Cash book"
+	}
+	switch {
+	case f.IsMultiCurrency() && f.HasVAT():
+		rsl += " multi-currency with VAT"
+	case f.IsMultiCurrency():
+		rsl += " multi-currency"
+	case f.HasVAT():
+		rsl += " with VAT"
+	}
+	return rsl
+}
+
+// Headers of the two account columns of the transaction table.
+func (f FileType) AccountHeaders() (string, string) {
+	switch f.Group {
+	case FILE_TYPE_INCOME_EXPENSE:
+		return "Konto", "Kategorie"
+	case FILE_TYPE_CASH_BOOK:
+		return "Kategorie", "Art"
+	}
+	return "Soll", "Haben"
+}
+
+// Content of the two account columns of the transaction table.
+func (f FileType) Accounts(tx Transaction) (string, string) {
+	switch f.Group {
+	case FILE_TYPE_INCOME_EXPENSE:
+		return tx.Account, tx.Category
+	case FILE_TYPE_CASH_BOOK:
+		kind := ""
+		if tx.Income != "" {
+			kind = "Einnahme"
+		} else if tx.Expenses != "" {
+			kind = "Ausgabe"
+		}
+		return tx.Category, kind
+	}
+	return tx.AccountDebit, tx.AccountCredit
+}
+
+// Forces cash basis accounting, a double-entry file is treated as income &
+// expense file.
+func (f FileType) asCashBasis() FileType {
+	if f.IsCashBasis() {
+		return f
+	}
+	rsl := FileType{Group: FILE_TYPE_INCOME_EXPENSE, Number: FILE_TYPE_NO_VAT}
+	if f.HasVAT() {
+		rsl.Number = FILE_TYPE_VAT
+	}
+	return rsl
+}
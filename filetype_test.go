@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFileTypeFromTables(t *testing.T) {
+	tests := []struct {
+		file     string
+		fileType FileType
+		headers  [2]string
+		// Account columns of the first booking.
+		accounts [2]string
+	}{
+		{
+			file:     "double-entry.xml",
+			fileType: FileType{Group: FILE_TYPE_DOUBLE_ENTRY, Number: FILE_TYPE_NO_VAT},
+			headers:  [2]string{"Soll", "Haben"},
+			accounts: [2]string{"6000", "1020"},
+		},
+		{
+			// Without file type in the file info, detected by the columns.
+			file:     "double-entry-vat.xml",
+			fileType: FileType{Group: FILE_TYPE_DOUBLE_ENTRY, Number: FILE_TYPE_VAT},
+			headers:  [2]string{"Soll", "Haben"},
+			accounts: [2]string{"6500", "1020"},
+		},
+		{
+			file:     "multi-currency.xml",
+			fileType: FileType{Group: FILE_TYPE_DOUBLE_ENTRY, Number: FILE_TYPE_MULTI_CURRENCY},
+			headers:  [2]string{"Soll", "Haben"},
+			accounts: [2]string{"6510", "1025"},
+		},
+		{
+			file:     "income-expense.xml",
+			fileType: FileType{Group: FILE_TYPE_INCOME_EXPENSE, Number: FILE_TYPE_NO_VAT},
+			headers:  [2]string{"Konto", "Kategorie"},
+			accounts: [2]string{"1020", "4000"},
+		},
+		{
+			file:     "cash-book.xml",
+			fileType: FileType{Group: FILE_TYPE_CASH_BOOK, Number: FILE_TYPE_NO_VAT},
+			headers:  [2]string{"Kategorie", "Art"},
+			accounts: [2]string{"4000", "Einnahme"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			ac, err := AC2FromFile(filepath.Join("testdata", test.file), AC2_TABLES...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fileInfo, err := ac.TableById("FileInfo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			journal, err := ac.TableById("Journal")
+			if err != nil {
+				t.Fatal(err)
+			}
+			fileType := FileTypeFromTables(*fileInfo, *journal)
+			if fileType != test.fileType {
+				t.Fatalf("detected %s (%+v), expected %s", fileType, fileType, test.fileType)
+			}
+			debitHeader, creditHeader := fileType.AccountHeaders()
+			if [2]string{debitHeader, creditHeader} != test.headers {
+				t.Errorf("headers %s, %s, expected %s", debitHeader, creditHeader, test.headers)
+			}
+			transactions := JournalFromTable(*journal)
+			if len(transactions) == 0 {
+				t.Fatal("no bookings")
+			}
+			debit, credit := fileType.Accounts(transactions[0])
+			if [2]string{debit, credit} != test.accounts {
+				t.Errorf("accounts %s, %s, expected %s", debit, credit, test.accounts)
+			}
+		})
+	}
+}
+
+func TestCashBookExpenseKind(t *testing.T) {
+	fileType := FileType{Group: FILE_TYPE_CASH_BOOK, Number: FILE_TYPE_NO_VAT}
+	category, kind := fileType.Accounts(Transaction{Category: "6500", Expenses: "12.00"})
+	if category != "6500" || kind != "Ausgabe" {
+		t.Errorf("accounts %s, %s, expected 6500, Ausgabe", category, kind)
+	}
+}
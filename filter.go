@@ -0,0 +1,166 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Operators of the booking filters, the two character ones first. "~"
+// matches cells containing the value (case insensitive).
+var FILTER_OPERATORS = []string{"!=", "<=", ">=", "=", "<", ">", "~"}
+
+// Condition on a column of the journal, e.g. "Cc3=MKT" or "Amount>=100".
+type BookingFilter struct {
+	Field    Field
+	Operator string
+	Value    string
+	// Value typed by the data type of the column.
+	typed any
+}
+
+// ParseBookingFilter reads a filter of the form <column><operator><value>,
+// the column is the field ID of a journal column. Exports without field
+// list compare all columns as text.
+func ParseBookingFilter(expr string, fields []Field) (BookingFilter, error) {
+	rsl := BookingFilter{}
+	for i := 0; i < len(expr) && rsl.Operator == ""; i++ {
+		for _, operator := range FILTER_OPERATORS {
+			if strings.HasPrefix(expr[i:], operator) {
+				rsl.Operator = operator
+				rsl.Field.ID = strings.TrimSpace(expr[:i])
+				rsl.Value = strings.TrimSpace(expr[i+len(operator):])
+				break
+			}
+		}
+	}
+	if rsl.Operator == "" || rsl.Field.ID == "" {
+		return rsl, fmt.Errorf("invalid filter '%s', expected <column><operator><value> with one of %s", expr, strings.Join(FILTER_OPERATORS, " "))
+	}
+	field, err := journalField(rsl.Field.ID, fields)
+	if err != nil {
+		return rsl, fmt.Errorf("invalid filter '%s': %w", expr, err)
+	}
+	rsl.Field = field
+	// An empty yes/no value is an unchecked box, other empty values only
+	// match empty cells.
+	if rsl.Operator == "~" || (rsl.Value == "" && field.Datatype != DATATYPE_BOOL) {
+		return rsl, nil
+	}
+	if rsl.typed, err = field.Parse(rsl.Value); err != nil {
+		return rsl, fmt.Errorf("invalid filter '%s': %w", expr, err)
+	}
+	if field.Datatype == DATATYPE_BOOL && rsl.Operator != "=" && rsl.Operator != "!=" {
+		return rsl, fmt.Errorf("invalid filter '%s': yes/no columns only support = and !=", expr)
+	}
+	return rsl, nil
+}
+
+// Column with the given ID, the case of the ID doesn't matter.
+func journalField(id string, fields []Field) (Field, error) {
+	if len(fields) == 0 {
+		return Field{ID: id, Datatype: DATATYPE_TEXT}, nil
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field.ID, id) {
+			return field, nil
+		}
+	}
+	return Field{}, fmt.Errorf("no journal column '%s'", id)
+}
+
+func (f BookingFilter) Matches(tx Transaction) (bool, error) {
+	raw := strings.TrimSpace(tx.Values[f.Field.ID])
+	if f.Operator == "~" {
+		return strings.Contains(strings.ToLower(raw), strings.ToLower(f.Value)), nil
+	}
+	// Empty cells and values are only equal to each other.
+	if f.Field.Datatype != DATATYPE_BOOL && (raw == "" || f.Value == "") {
+		switch f.Operator {
+		case "=":
+			return raw == f.Value, nil
+		case "!=":
+			return raw != f.Value, nil
+		}
+		return false, nil
+	}
+	value, err := f.Field.Parse(raw)
+	if err != nil {
+		return false, fmt.Errorf("%w of booking %s", err, tx.Unique)
+	}
+	rsl := compareValues(value, f.typed)
+	switch f.Operator {
+	case "=":
+		return rsl == 0, nil
+	case "!=":
+		return rsl != 0, nil
+	case "<":
+		return rsl < 0, nil
+	case "<=":
+		return rsl <= 0, nil
+	case ">":
+		return rsl > 0, nil
+	}
+	return rsl >= 0, nil
+}
+
+// Compares two values of the same type as returned by Field.Parse.
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case bool:
+		if a == b.(bool) {
+			return 0
+		}
+		return 1
+	}
+	return strings.Compare(a.(string), b.(string))
+}
+
+// FilterBookings only keeps the bookings matching all of the filters in the
+// report, receipts without matching booking are left out. The statements
+// and the cash basis summary still cover all bookings of the file.
+func (d *Dossier) FilterBookings(exprs []string) error {
+	filters := []BookingFilter{}
+	for _, expr := range exprs {
+		filter, err := ParseBookingFilter(expr, d.JournalFields)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, filter)
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	rsl := Documents{}
+	for _, doc := range d.JournalEntries {
+		transactions := Transactions{}
+		for _, tx := range doc.Transactions {
+			matches := true
+			for _, filter := range filters {
+				ok, err := filter.Matches(tx)
+				if err != nil {
+					fmt.Printf("%s: %s\n", doc.Path, err)
+				}
+				if !ok {
+					matches = false
+					break
+				}
+			}
+			if matches {
+				transactions = append(transactions, tx)
+			}
+		}
+		if len(transactions) == 0 {
+			continue
+		}
+		doc.Transactions = transactions
+		rsl = append(rsl, doc)
+	}
+	d.JournalEntries = rsl
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestBookingFilterMatches(t *testing.T) {
+	fields := []Field{
+		{ID: "Cc3", Datatype: DATATYPE_TEXT},
+		{ID: "Amount", Datatype: DATATYPE_AMOUNT},
+		{ID: "Date", Datatype: DATATYPE_DATE},
+		{ID: "Approved", Datatype: DATATYPE_BOOL},
+	}
+	booking := func(values map[string]string) Transaction {
+		return Transaction{Unique: "1", Values: values}
+	}
+	tests := []struct {
+		expr    string
+		values  map[string]string
+		matches bool
+	}{
+		{"Cc3=MKT", map[string]string{"Cc3": "MKT"}, true},
+		{"Cc3=MKT", map[string]string{"Cc3": "ADM"}, false},
+		{"Cc3=", map[string]string{}, true},
+		{"Cc3!=", map[string]string{"Cc3": "MKT"}, true},
+		{"Amount>=100", map[string]string{"Amount": "100.00"}, true},
+		{"Amount<100", map[string]string{"Amount": "100.00"}, false},
+		{"Amount>0", map[string]string{}, false},
+		{"Date<01.03.2023", map[string]string{"Date": "2023-02-28"}, true},
+		{"Approved=1", map[string]string{"Approved": "1"}, true},
+		{"Approved=1", map[string]string{}, false},
+		// Empty yes/no values are unchecked boxes.
+		{"Approved=", map[string]string{}, true},
+		{"Approved=", map[string]string{"Approved": "1"}, false},
+		{"Approved!=", map[string]string{"Approved": "1"}, true},
+		{"Approved!=", map[string]string{"Approved": "0"}, false},
+	}
+	for _, test := range tests {
+		filter, err := ParseBookingFilter(test.expr, fields)
+		if err != nil {
+			t.Errorf("%s: %s", test.expr, err)
+			continue
+		}
+		matches, err := filter.Matches(booking(test.values))
+		if err != nil {
+			t.Errorf("%s: %s", test.expr, err)
+			continue
+		}
+		if matches != test.matches {
+			t.Errorf("%s matches %v: %v, expected %v", test.expr, test.values, matches, test.matches)
+		}
+	}
+}
+
+func TestParseBookingFilterErrors(t *testing.T) {
+	fields := []Field{{ID: "Amount", Datatype: DATATYPE_AMOUNT}, {ID: "Approved", Datatype: DATATYPE_BOOL}}
+	for _, expr := range []string{"Amount", "=100", "Unknown=1", "Amount>abc", "Approved<1", "Approved>"} {
+		if _, err := ParseBookingFilter(expr, fields); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
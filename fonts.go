@@ -0,0 +1,409 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// Font sizes of the report sections in pt.
+type FontSizes struct {
+	// Description below the title, the title is 1.8 times larger.
+	Header float64
+	// Transaction table and the info rows below.
+	Table  float64
+	Footer float64
+}
+
+// Fonts of the report, each given as path to a TrueType/OpenType file or as
+// name of an installed font. Empty entries use the embedded Literata, empty
+// italic and bold entries use the regular font.
+type FontConfig struct {
+	Regular string
+	Italic  string
+	Bold    string
+	// Tried in order for text with characters missing in the main font
+	// (e.g. currency symbols or CJK supplier names).
+	Fallbacks []string
+	Sizes     FontSizes
+}
+
+// Loaded font file.
+type Font struct {
+	// Family name stored in the font, used by typst to select the font.
+	Family   string
+	FileName string
+	Data     []byte
+	face     *sfnt.Font
+}
+
+// Loaded fonts of the report.
+type Fonts struct {
+	Regular   *Font
+	Italic    *Font
+	Bold      *Font
+	Fallbacks []*Font
+	Sizes     FontSizes
+}
+
+// Load reads all configured fonts.
+func (c FontConfig) Load() (Fonts, error) {
+	rsl := Fonts{Sizes: c.Sizes}
+	if rsl.Sizes.Header <= 0 || rsl.Sizes.Table <= 0 || rsl.Sizes.Footer <= 0 {
+		return rsl, fmt.Errorf("invalid font sizes, header, table and footer size have to be positive")
+	}
+	var err error
+	if c.Regular == "" {
+		if rsl.Regular, err = newFont("literata-regular.ttf", literataRegular); err != nil {
+			return rsl, err
+		}
+		if rsl.Italic, err = newFont("literata-italic.ttf", literataItalic); err != nil {
+			return rsl, err
+		}
+		if rsl.Bold, err = newFont("literata-medium.ttf", literataMedium); err != nil {
+			return rsl, err
+		}
+	} else if rsl.Regular, err = LoadFont(c.Regular); err != nil {
+		return rsl, err
+	}
+	for _, style := range []struct {
+		spec string
+		font **Font
+	}{{c.Italic, &rsl.Italic}, {c.Bold, &rsl.Bold}} {
+		if style.spec != "" {
+			if *style.font, err = LoadFont(style.spec); err != nil {
+				return rsl, err
+			}
+		} else if *style.font == nil {
+			*style.font = rsl.Regular
+		}
+	}
+	for _, spec := range c.Fallbacks {
+		font, err := LoadFont(spec)
+		if err != nil {
+			return rsl, err
+		}
+		rsl.Fallbacks = append(rsl.Fallbacks, font)
+	}
+	return rsl, nil
+}
+
+// Family names of the main font followed by the fallbacks.
+func (f Fonts) Families() []string {
+	rsl := []string{f.Regular.Family}
+	for _, fallback := range f.Fallbacks {
+		rsl = append(rsl, fallback.Family)
+	}
+	return rsl
+}
+
+// LoadFont reads the font at the given path or, if there is no such file,
+// searches the installed fonts for the given name.
+func LoadFont(spec string) (*Font, error) {
+	path := spec
+	if _, err := os.Stat(path); err != nil {
+		if path, err = findSystemFont(spec); err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rsl, err := newFont(filepath.Base(path), data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font '%s': %w", path, err)
+	}
+	return rsl, nil
+}
+
+func newFont(fileName string, data []byte) (*Font, error) {
+	face, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	rsl := &Font{FileName: fileName, Data: data, face: face}
+	// The typographic family groups weights like "Medium" with the regular
+	// face, typst selects fonts by this name.
+	for _, id := range []sfnt.NameID{sfnt.NameIDTypographicFamily, sfnt.NameIDFamily} {
+		if family, err := face.Name(nil, id); err == nil && family != "" {
+			rsl.Family = family
+			break
+		}
+	}
+	if rsl.Family == "" {
+		return nil, fmt.Errorf("font has no family name")
+	}
+	return rsl, nil
+}
+
+// HasGlyphs reports whether the font contains all printable characters of
+// the text.
+func (f *Font) HasGlyphs(txt string) bool {
+	var buf sfnt.Buffer
+	for _, r := range txt {
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			continue
+		}
+		if index, err := f.face.GlyphIndex(&buf, r); err != nil || index == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Directories with the installed fonts of the current platform.
+func systemFontDirs() []string {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"/System/Library/Fonts", "/Library/Fonts", filepath.Join(home, "Library", "Fonts")}
+	case "windows":
+		return []string{
+			filepath.Join(os.Getenv("WINDIR"), "Fonts"),
+			filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "Windows", "Fonts"),
+		}
+	}
+	return []string{
+		"/usr/share/fonts",
+		"/usr/local/share/fonts",
+		filepath.Join(home, ".local", "share", "fonts"),
+		filepath.Join(home, ".fonts"),
+	}
+}
+
+// Returns the path of the installed font with the given full name (e.g.
+// "Noto Sans Bold") or, if there is none, the regular face of the family
+// with the given name. Font collections (.ttc) are not supported.
+func findSystemFont(name string) (string, error) {
+	fullMatch, familyMatch := "", ""
+	for _, dir := range systemFontDirs() {
+		if fullMatch != "" {
+			break
+		}
+		filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			ext := strings.ToLower(filepath.Ext(path))
+			if ext != ".ttf" && ext != ".otf" {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			face, err := sfnt.Parse(data)
+			if err != nil {
+				return nil
+			}
+			if full, err := face.Name(nil, sfnt.NameIDFull); err == nil && strings.EqualFold(full, name) {
+				fullMatch = path
+				return fs.SkipAll
+			}
+			family, _ := face.Name(nil, sfnt.NameIDFamily)
+			subfamily, _ := face.Name(nil, sfnt.NameIDSubfamily)
+			if familyMatch == "" && strings.EqualFold(family, name) && strings.EqualFold(subfamily, "Regular") {
+				familyMatch = path
+			}
+			return nil
+		})
+	}
+	if fullMatch != "" {
+		return fullMatch, nil
+	}
+	if familyMatch == "" {
+		return "", fmt.Errorf("font '%s' is neither a file nor an installed font", name)
+	}
+	return familyMatch, nil
+}
+
+// Name of the fpdf font family of the fallback with the given index.
+func fallbackFamily(index int) string {
+	return fmt.Sprintf("fallback-%d", index)
+}
+
+// SetFont also remembers the style for switching to a fallback font.
+func (pdf PDF) SetFont(familyStr, styleStr string, size float64) {
+	*pdf.fontStyle = styleStr
+	pdf.Fpdf.SetFont(familyStr, styleStr, size)
+}
+
+// Font of the given fpdf style ("", "B" or "I").
+func (f Fonts) ForStyle(style string) *Font {
+	switch {
+	case strings.Contains(style, "B"):
+		return f.Bold
+	case strings.Contains(style, "I"):
+		return f.Italic
+	}
+	return f.Regular
+}
+
+// Part of a text set in one font. Fallback is the index of the fallback
+// font, -1 for the main font of the current style.
+type fontRun struct {
+	txt      string
+	fallback int
+}
+
+// Splits the text into runs of characters set in the same font. Each
+// character uses the main font of the current style if it has the glyph and
+// the first fallback font having it otherwise. Spaces stay in the run before.
+func (pdf PDF) fontRuns(txt string) []fontRun {
+	font := pdf.fonts.ForStyle(*pdf.fontStyle)
+	if font == nil || len(pdf.fonts.Fallbacks) == 0 || font.HasGlyphs(txt) {
+		return []fontRun{{txt: txt, fallback: -1}}
+	}
+	rsl := []fontRun{}
+	for _, r := range txt {
+		last := len(rsl) - 1
+		if last != -1 && unicode.IsSpace(r) {
+			rsl[last].txt += string(r)
+			continue
+		}
+		fallback := -1
+		if !font.HasGlyphs(string(r)) {
+			for i, candidate := range pdf.fonts.Fallbacks {
+				if candidate.HasGlyphs(string(r)) {
+					fallback = i
+					break
+				}
+			}
+		}
+		if last != -1 && rsl[last].fallback == fallback {
+			rsl[last].txt += string(r)
+		} else {
+			rsl = append(rsl, fontRun{txt: string(r), fallback: fallback})
+		}
+	}
+	return rsl
+}
+
+// Whether the text is set in the main font only.
+func inMainFont(runs []fontRun) bool {
+	return len(runs) == 1 && runs[0].fallback == -1
+}
+
+// Calls draw for each run with the font of the run set and restores the
+// main font afterwards.
+func (pdf PDF) withRunFonts(runs []fontRun, draw func(txt string)) {
+	size, _ := pdf.GetFontSize()
+	style := *pdf.fontStyle
+	for _, run := range runs {
+		family := pdf.FontFamily
+		if run.fallback != -1 {
+			family = fallbackFamily(run.fallback)
+		}
+		pdf.Fpdf.SetFont(family, style, size)
+		draw(run.txt)
+	}
+	pdf.Fpdf.SetFont(pdf.FontFamily, style, size)
+}
+
+// CellFormat of fpdf, characters missing in the main font are set in the
+// fallback fonts.
+func (pdf PDF) CellFormat(w, h float64, txtStr, borderStr string, ln int, alignStr string, fill bool, link int, linkStr string) {
+	runs := pdf.fontRuns(txtStr)
+	if inMainFont(runs) {
+		pdf.Fpdf.CellFormat(w, h, txtStr, borderStr, ln, alignStr, fill, link, linkStr)
+		return
+	}
+	left, _, right, _ := pdf.GetMargins()
+	if w == 0 {
+		pageWidth, _ := pdf.GetPageSize()
+		w = pageWidth - right - pdf.GetX()
+	}
+	// The cell without the text first, it breaks the page if needed.
+	pdf.Fpdf.CellFormat(w, h, "", borderStr, 0, "", fill, link, linkStr)
+	x, y := pdf.GetX()-w, pdf.GetY()
+
+	margin := pdf.GetCellMargin()
+	width := pdf.GetStringWidth(txtStr)
+	switch {
+	case strings.Contains(alignStr, "R"):
+		pdf.SetX(x + w - margin - width)
+	case strings.Contains(alignStr, "C"):
+		pdf.SetX(x + (w-width)/2)
+	default:
+		pdf.SetX(x + margin)
+	}
+	vertical := strings.Map(func(r rune) rune {
+		if strings.ContainsRune("TMBA", r) {
+			return r
+		}
+		return -1
+	}, alignStr)
+	pdf.SetCellMargin(0)
+	pdf.withRunFonts(runs, func(txt string) {
+		pdf.Fpdf.CellFormat(pdf.Fpdf.GetStringWidth(txt), h, txt, "", 0, "L"+vertical, false, 0, "")
+	})
+	pdf.SetCellMargin(margin)
+
+	switch ln {
+	case 0:
+		pdf.SetXY(x+w, y)
+	case 1:
+		pdf.SetXY(left, y+h)
+	case 2:
+		pdf.SetXY(x, y+h)
+	}
+}
+
+// MultiCell of fpdf, characters missing in the main font are set in the
+// fallback fonts. Justified text is aligned left in this case.
+func (pdf PDF) MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill bool) {
+	if inMainFont(pdf.fontRuns(txtStr)) {
+		pdf.Fpdf.MultiCell(w, h, txtStr, borderStr, alignStr, fill)
+		return
+	}
+	left, _, right, _ := pdf.GetMargins()
+	if w == 0 {
+		pageWidth, _ := pdf.GetPageSize()
+		w = pageWidth - right - pdf.GetX()
+	}
+	if borderStr == "1" {
+		borderStr = "LTRB"
+	}
+	sides := ""
+	for _, side := range []string{"L", "R"} {
+		if strings.Contains(borderStr, side) {
+			sides += side
+		}
+	}
+	alignStr = strings.ReplaceAll(alignStr, "J", "")
+	lines := []string{}
+	txtStr = strings.TrimRight(strings.ReplaceAll(txtStr, "\r", ""), "\n")
+	for _, paragraph := range strings.Split(txtStr, "\n") {
+		lines = append(lines, pdf.wrapText(paragraph, w-2*pdf.GetCellMargin())...)
+	}
+	for i, line := range lines {
+		border := sides
+		if i == 0 && strings.Contains(borderStr, "T") {
+			border += "T"
+		}
+		if i == len(lines)-1 && strings.Contains(borderStr, "B") {
+			border += "B"
+		}
+		pdf.CellFormat(w, h, line, border, 2, alignStr, fill, 0, "")
+	}
+	pdf.SetX(left)
+}
+
+func (pdf PDF) GetStringWidth(s string) float64 {
+	runs := pdf.fontRuns(s)
+	if inMainFont(runs) {
+		return pdf.Fpdf.GetStringWidth(s)
+	}
+	rsl := 0.0
+	pdf.withRunFonts(runs, func(txt string) {
+		rsl += pdf.Fpdf.GetStringWidth(txt)
+	})
+	return rsl
+}
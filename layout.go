@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Supported page sizes, fpdf name mapped to the typst paper name.
+var PAGE_SIZES = map[string]string{
+	"A3":     "a3",
+	"A4":     "a4",
+	"A5":     "a5",
+	"Letter": "us-letter",
+	"Legal":  "us-legal",
+}
+
+// Page margins in mm.
+type Margins struct {
+	Top    float64
+	Right  float64
+	Bottom float64
+	Left   float64
+}
+
+// Page size, orientation and margins of the report.
+type PageSetup struct {
+	Size      string
+	Landscape bool
+	Margins   Margins
+}
+
+// ParsePageSetup validates the page size (case-insensitive) and parses the
+// margins given like in CSS: one value for all sides, two values for
+// vertical and horizontal or four values for top, right, bottom and left.
+func ParsePageSetup(size string, landscape bool, margins string) (PageSetup, error) {
+	rsl := PageSetup{Landscape: landscape}
+	for name := range PAGE_SIZES {
+		if strings.EqualFold(name, size) {
+			rsl.Size = name
+		}
+	}
+	if rsl.Size == "" {
+		return rsl, fmt.Errorf("invalid page size '%s', available sizes: A3, A4, A5, Letter, Legal", size)
+	}
+
+	values := []float64{}
+	for _, part := range strings.Split(margins, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || value < 0 {
+			return rsl, fmt.Errorf("invalid margins '%s'", margins)
+		}
+		values = append(values, value)
+	}
+	switch len(values) {
+	case 1:
+		rsl.Margins = Margins{values[0], values[0], values[0], values[0]}
+	case 2:
+		rsl.Margins = Margins{values[0], values[1], values[0], values[1]}
+	case 4:
+		rsl.Margins = Margins{values[0], values[1], values[2], values[3]}
+	default:
+		return rsl, fmt.Errorf("invalid margins '%s', expected one, two or four values", margins)
+	}
+	return rsl, nil
+}
+
+func (p PageSetup) Orientation() string {
+	if p.Landscape {
+		return "L"
+	}
+	return "P"
+}
+
+// Margins in the format of the typst input (top,right,bottom,left).
+func (m Margins) String() string {
+	return fmt.Sprintf("%g,%g,%g,%g", m.Top, m.Right, m.Bottom, m.Left)
+}
+
+// Widths of the columns of the transaction table. The layout was designed
+// for the 190 mm wide area of an A4 portrait page and is scaled to the
+// actual area width.
+type tableColumns struct {
+	Ident       float64
+	Date        float64
+	Description float64
+	Debit       float64
+	Credit      float64
+	// VAT code, only shown for files with VAT.
+	Vat float64
+	// Each of the extra journal columns (--columns).
+	Extra  float64
+	Amount float64
+}
+
+func newTableColumns(areaWidth float64, fileType FileType, extraColumns int) tableColumns {
+	unit := areaWidth / 190
+	rsl := tableColumns{
+		Ident:       23 * unit,
+		Date:        14 * unit,
+		Description: 103.49 * unit,
+		Debit:       14 * unit,
+		Credit:      14 * unit,
+		Amount:      20 * unit,
+	}
+	if fileType.HasVAT() {
+		rsl.Vat = 10 * unit
+		rsl.Description -= rsl.Vat
+	}
+	if extraColumns != 0 {
+		rsl.Extra = 18 * unit
+		rsl.Description -= float64(extraColumns) * rsl.Extra
+	}
+	return rsl
+}
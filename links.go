@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// Other document sharing a doc number (Beleg) with a document.
+type RelatedDocument struct {
+	Ident string
+	Path  string
+	Title string
+}
+
+// LinkDocuments sets the related documents of each document and, if
+// fileLinks is set, the file:// URL of the original receipt.
+func (d *Dossier) LinkDocuments(fileLinks bool) {
+	byIdent := map[string][]int{}
+	for i, doc := range d.JournalEntries {
+		for _, ident := range doc.Idents() {
+			if ident != "" {
+				byIdent[ident] = append(byIdent[ident], i)
+			}
+		}
+	}
+	for i := range d.JournalEntries {
+		doc := &d.JournalEntries[i]
+		doc.Related = []RelatedDocument{}
+		for _, ident := range doc.Idents() {
+			for _, j := range byIdent[ident] {
+				if j == i {
+					continue
+				}
+				other := d.JournalEntries[j]
+				doc.Related = append(doc.Related, RelatedDocument{
+					Ident: ident,
+					Path:  other.Path,
+					Title: other.Title(),
+				})
+			}
+		}
+		doc.FileURL = ""
+		if fileLinks && doc.IsValidFile {
+			doc.FileURL = fileURL(doc.AbsolutePath)
+		}
+	}
+}
+
+// Related documents of the given doc number.
+func (d Document) RelatedByIdent(ident string) []RelatedDocument {
+	rsl := []RelatedDocument{}
+	for _, related := range d.Related {
+		if related.Ident == ident {
+			rsl = append(rsl, related)
+		}
+	}
+	return rsl
+}
+
+// Title of the document shown in the header, the outline and the links.
+func (d Document) Title() string {
+	return strings.TrimSuffix(filepath.Base(d.Path), filepath.Ext(d.Path))
+}
+
+func fileURL(path string) string {
+	rsl := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	if !strings.HasPrefix(rsl.Path, "/") {
+		// Windows drive letter.
+		rsl.Path = "/" + rsl.Path
+	}
+	return rsl.String()
+}
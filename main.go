@@ -8,41 +8,178 @@ import (
 
 func main() {
 	var args struct {
-		InputPath        string `cli:"#R, -i, --input, path to Banana XML file"`
-		OutputPath       string `cli:"#R, -o, --output, PDF output path"`
-		CashBasisAccount bool   `cli:"--cash-basis, switch to cash basis account (EÜR)"`
-		Engine           string `cli:"--engine, engine to use for PDF generation (typst, fpdf)" default:"typst"`
-		DebugCells       bool   `cli:"--debug-cells, enable debug mode for PDF cells"`
-		DebugLines       bool   `cli:"--debug-lines, enable debug mode for PDF lines"`
-		DebugTempDir     bool   `cli:"--debug-temp-dir, open temp dir in Finder (for typst)"`
-		TypstDebug       bool   `cli:"--typst-debug, enable debug mode for typst template"`
-		StepEmbedError   bool   `cli:"--step-embed-error, stop on embed error and open file"`
+		InputPath        string   `cli:"#R, -i, --input, path to Banana XML file"`
+		OutputPath       string   `cli:"#R, -o, --output, PDF output path"`
+		CashBasisAccount bool     `cli:"--cash-basis, force cash basis accounting (EÜR), detected from the file by default"`
+		Engine           string   `cli:"--engine, engine to use for PDF generation (typst, fpdf)" default:"typst"`
+		DebugCells       bool     `cli:"--debug-cells, enable debug mode for PDF cells"`
+		DebugLines       bool     `cli:"--debug-lines, enable debug mode for PDF lines"`
+		DebugTempDir     bool     `cli:"--debug-temp-dir, open temp dir in Finder (for typst)"`
+		TypstDebug       bool     `cli:"--typst-debug, enable debug mode for typst template"`
+		StepEmbedError   bool     `cli:"--step-embed-error, stop on embed error and open file"`
+		Verify           bool     `cli:"--verify, search the receipts for the booked amounts and dates and mark missing ones in the report (fpdf only)"`
+		QRBill           bool     `cli:"--qr-bill, detect Swiss QR-bills on the receipts and compare them with the bookings"`
+		AttachOriginals  bool     `cli:"--attach-originals, embed the original receipt files as attachments"`
+		PDFA             bool     `cli:"--pdfa, produce a PDF/A-3b document for long-term archiving"`
+		Filters          []string `cli:"--filter, only report bookings matching the filter, e.g. 'Cc3=MKT', 'Amount>=100' or 'Notes~approved', can be repeated"`
+		OutlineGroup     string   `cli:"--outline-group, group the bookmarks by month, account, directory or none" default:"none"`
+		TOC              bool     `cli:"--toc, start the report with a linked table of contents"`
+		FileLinks        bool     `cli:"--file-links, link the headers to the original receipts on disk (file://)"`
+		Statements       []string `cli:"--statement, path pattern of account statements (e.g. 'bank/*.pdf'), can be repeated (fpdf only)"`
+		StatementAccount []string `cli:"--statement-account, account whose receipts linked from several bookings are statements, can be repeated (fpdf only)"`
+		TrimReceipts     bool     `cli:"--trim-receipts, cut the white margins of scanned receipts (fpdf only)"`
+		Columns          []string `cli:"--columns, journal column shown in the transaction table, e.g. Notes or a custom column, can be repeated (fpdf only)"`
+		NotesFootnotes   bool     `cli:"--notes-footnotes, print notes too long for the table as footnotes below it (fpdf only)"`
+		TruncateDesc     bool     `cli:"--truncate-descriptions, cut long descriptions in the transaction table instead of wrapping them"`
+		RateDecimals     int      `cli:"--exchange-rate-decimals, decimal places of the exchange rates in the transaction table" default:"4"`
+		RateDeviation    float64  `cli:"--max-rate-deviation, highlight foreign currency bookings whose rate deviates more than this percentage from the exchange rates table" default:"2"`
+		PageSize         string   `cli:"--page-size, page size (A3, A4, A5, Letter, Legal)" default:"A4"`
+		Landscape        bool     `cli:"--landscape, use landscape pages"`
+		Margins          string   `cli:"--margins, page margins in mm: all, vertical,horizontal or top,right,bottom,left" default:"10"`
+		Font             string   `cli:"--font, regular font, path to a TTF/OTF file or name of an installed font (default: Literata)"`
+		FontItalic       string   `cli:"--font-italic, italic font (default: regular font)"`
+		FontBold         string   `cli:"--font-bold, bold font (default: regular font)"`
+		FontFallbacks    []string `cli:"--font-fallback, font for characters missing in the main font, each character uses the first fallback having it, can be repeated"`
+		HeaderFontSize   float64  `cli:"--header-font-size, font size of the header in pt" default:"10"`
+		TableFontSize    float64  `cli:"--table-font-size, font size of the transaction table in pt" default:"7"`
+		FooterFontSize   float64  `cli:"--footer-font-size, font size of the footer in pt" default:"6.8"`
+		QRPayload        string   `cli:"--qr-payload, content of the header QR code, placeholders {path} {abspath} {file} {title} {ident} {unique} {date} {amount} {currency} or json" default:"{path}"`
 	}
 	mcli.Parse(&args)
+	page, err := ParsePageSetup(args.PageSize, args.Landscape, args.Margins)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fonts, err := FontConfig{
+		Regular:   args.Font,
+		Italic:    args.FontItalic,
+		Bold:      args.FontBold,
+		Fallbacks: args.FontFallbacks,
+		Sizes: FontSizes{
+			Header: args.HeaderFontSize,
+			Table:  args.TableFontSize,
+			Footer: args.FooterFontSize,
+		},
+	}.Load()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 	dossier, err := DossierFromXML(args.InputPath)
 	if err != nil {
 		fmt.Println(err)
+		return
 	}
-	if args.Engine == "typst" {
-		typst, err := NewTypst(dossier, args.OutputPath, args.TypstDebug)
-		if err != nil {
+	dossier.SetCashBasisAccounting(args.CashBasisAccount)
+	if err := dossier.FilterBookings(args.Filters); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := dossier.GroupForOutline(args.OutlineGroup, dossier.FileType.IsCashBasis()); err != nil {
+		fmt.Println(err)
+	}
+	dossier.ResolveAccounts(dossier.FileType.IsCashBasis())
+	if dossier.FileType.IsCashBasis() {
+		dossier.ResolveCategories()
+	}
+	dossier.CompareExchangeRates(args.RateDeviation)
+	dossier.LinkDocuments(args.FileLinks)
+	if err := dossier.SetQRPayloads(args.QRPayload); err != nil {
+		fmt.Println(err)
+	}
+	if args.Verify {
+		dossier.Verify()
+		dossier.PrintVerificationIssues()
+	}
+	if args.QRBill {
+		dossier.DetectQRBills()
+	}
+	if len(args.Statements) != 0 || len(args.StatementAccount) != 0 {
+		if err := dossier.DetectStatements(args.Statements, args.StatementAccount, dossier.FileType.IsCashBasis()); err != nil {
 			fmt.Println(err)
 		}
-		err = typst.Build(args.DebugTempDir)
+	}
+	if args.TrimReceipts {
+		dossier.TrimReceipts()
+	}
+	if args.PDFA {
+		dossier.CheckPDFA()
+	}
+	if args.Engine == "typst" {
+		typst, err := NewTypst(dossier, args.OutputPath, TypstOptions{
+			DebugMode:       args.TypstDebug,
+			AttachOriginals: args.AttachOriginals,
+			PDFA:            args.PDFA,
+			TableOfContents: args.TOC,
+			Page:            page,
+			Fonts:           fonts,
+		})
 		if err != nil {
 			fmt.Println(err)
 		}
+		buildErr := typst.Build(args.DebugTempDir)
+		if buildErr != nil {
+			fmt.Println(buildErr)
+		}
 		err = typst.Close()
 		if err != nil {
 			fmt.Println(err)
 		}
+		if buildErr == nil {
+			// typst only writes the standard keys of the document info. pdfcpu
+			// changes the producer, the XMP metadata is rewritten to match.
+			err = PostProcessPDF(
+				args.OutputPath,
+				SetCustomInfo(dossier.DocumentInfo().Custom),
+				AddXMPMetadata(args.PDFA),
+			)
+			if err != nil {
+				fmt.Println(err)
+			}
+		}
 	} else if args.Engine == "fpdf" {
-		pdf := NewPDF(args.CashBasisAccount, args.DebugCells, args.DebugLines, args.StepEmbedError)
+		columns, err := dossier.ExtraColumns(args.Columns)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		pdf := NewPDF(PDFOptions{
+			FileType:             dossier.FileType,
+			DebugCells:           args.DebugCells,
+			DebugLines:           args.DebugLines,
+			StepEmbedError:       args.StepEmbedError,
+			AttachOriginals:      args.AttachOriginals,
+			TableOfContents:      args.TOC,
+			TruncateDescriptions: args.TruncateDesc,
+			ExchangeRateDecimals: args.RateDecimals,
+			MaxRateDeviation:     args.RateDeviation,
+			Columns:              columns,
+			NotesFootnotes:       args.NotesFootnotes,
+			Page:                 page,
+			Fonts:                fonts,
+		})
 		pdf.Build(dossier)
 		err = pdf.OutputFileAndClose(args.OutputPath)
 		if err != nil {
 			fmt.Println(err)
 		}
+		steps := []PostProcessStep{
+			SetCustomInfo(dossier.DocumentInfo().Custom),
+			SetPageLabels(pdf.PageLabels()),
+		}
+		if args.AttachOriginals {
+			steps = append(steps, AttachOriginals(pdf.Originals()))
+		}
+		if args.PDFA {
+			steps = append(steps, ConvertToPDFA())
+		}
+		// Has to be the last step.
+		steps = append(steps, AddXMPMetadata(args.PDFA))
+		err = PostProcessPDF(args.OutputPath, steps...)
+		if err != nil {
+			fmt.Println(err)
+		}
 	} else {
 		fmt.Println("invalid engine, available engines: typst, fpdf")
 	}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+const CREATOR = "banana-report"
+
+// Content of the document information dictionary of the report.
+type DocumentInfo struct {
+	Title    string
+	Author   string
+	Subject  string
+	Keywords string
+	Creator  string
+	// Additional keys, e.g. the hash of the source XML.
+	Custom map[string]string
+}
+
+func (d Dossier) DocumentInfo() DocumentInfo {
+	title := []string{"Receipts"}
+	if d.CompanyName != "" {
+		title = append(title, d.CompanyName)
+	}
+	title = append(title, d.FmtPeriod())
+
+	keywords := []string{"Receipts", "Accounting"}
+	if d.CompanyName != "" {
+		keywords = append(keywords, d.CompanyName)
+	}
+	if !d.OpeningDate.Equal(time.Time{}) {
+		keywords = append(keywords, d.OpeningDate.Format("2006"))
+	}
+	if !d.ClosureDate.Equal(time.Time{}) && d.ClosureDate.Year() != d.OpeningDate.Year() {
+		keywords = append(keywords, d.ClosureDate.Format("2006"))
+	}
+
+	return DocumentInfo{
+		Title:  strings.Join(title, " – "),
+		Author: d.CompanyName,
+		Subject: fmt.Sprintf(
+			"Receipts of the accounting file %s, accounting data as of %s",
+			filepath.Base(d.AccountingFilePath), d.FmtLastSaved(),
+		),
+		Keywords: strings.Join(keywords, ", "),
+		Creator:  CREATOR,
+		Custom: map[string]string{
+			"AccountingFile":     filepath.Base(d.AccountingFilePath),
+			"AccountingPeriod":   d.FmtPeriod(),
+			"AccountingDataAsOf": d.FmtLastSaved(),
+			"SourceFile":         filepath.Base(d.SourcePath),
+			"SourceSHA256":       d.SourceSHA256,
+		},
+	}
+}
+
+func (i DocumentInfo) ToJSON(path string) error {
+	data, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Hex encoded SHA-256 hash of the file at the given path.
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// SetCustomInfo adds the given keys to the document information dictionary.
+// Neither fpdf nor typst support other than the standard keys.
+func SetCustomInfo(custom map[string]string) PostProcessStep {
+	return func(ctx *model.Context) error {
+		if len(custom) == 0 {
+			return nil
+		}
+		infoDict, err := documentInfoDict(ctx)
+		if err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(custom))
+		for key := range custom {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, err := types.EscapedUTF16String(custom[key])
+			if err != nil {
+				return err
+			}
+			infoDict.Update(key, types.StringLiteral(*value))
+		}
+		return nil
+	}
+}
+
+// Document information dictionary of the PDF, created if missing.
+func documentInfoDict(ctx *model.Context) (types.Dict, error) {
+	if ctx.Info == nil {
+		infoRef, err := ctx.IndRefForNewObject(types.NewDict())
+		if err != nil {
+			return nil, err
+		}
+		ctx.Info = infoRef
+	}
+	infoDict, err := ctx.DereferenceDict(*ctx.Info)
+	if err != nil || infoDict == nil {
+		return nil, fmt.Errorf("failed to read document info: %w", err)
+	}
+	return infoDict, nil
+}
+
+// Page label range, applies from Page (1-based) up to the next label.
+type PageLabel struct {
+	Page   int
+	Prefix string
+	// Numbering style (D, R, r, A or a), only the prefix if empty.
+	Style string
+	// First number of the range, defaults to 1.
+	Start int
+}
+
+// SetPageLabels writes the page labels shown by viewers instead of the
+// physical page number.
+func SetPageLabels(labels []PageLabel) PostProcessStep {
+	return func(ctx *model.Context) error {
+		if len(labels) == 0 {
+			return nil
+		}
+		nums := types.Array{}
+		for _, label := range labels {
+			dict := types.Dict{}
+			if label.Style != "" {
+				dict.InsertName("S", label.Style)
+			}
+			if label.Prefix != "" {
+				prefix, err := types.EscapedUTF16String(label.Prefix)
+				if err != nil {
+					return err
+				}
+				dict.Insert("P", types.StringLiteral(*prefix))
+			}
+			if label.Start > 1 {
+				dict.InsertInt("St", label.Start)
+			}
+			nums = append(nums, types.Integer(label.Page-1), dict)
+		}
+		rootDict, err := ctx.Catalog()
+		if err != nil {
+			return err
+		}
+		rootDict.Update("PageLabels", types.Dict{"Nums": nums})
+		return nil
+	}
+}
+
+// AddXMPMetadata writes the XMP metadata stream mirroring the document
+// information dictionary, including the PDF/A identification if pdfa is set.
+// The creation and modification date of both are set to the current time.
+// Run it as the last step so that the XMP metadata matches the final
+// document info.
+func AddXMPMetadata(pdfa bool) PostProcessStep {
+	return func(ctx *model.Context) error {
+		infoDict, err := documentInfoDict(ctx)
+		if err != nil {
+			return err
+		}
+		info := map[string]string{}
+		for _, key := range []string{"Title", "Author", "Subject", "Keywords", "Creator"} {
+			if value, ok := infoDict[key]; ok {
+				info[key], err = ctx.DereferenceStringOrHexLiteral(value, model.V10, nil)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// PostProcessPDF keeps these dates when pdfcpu writes the file.
+		now := time.Now().Truncate(time.Second)
+		infoDict.Update("CreationDate", types.StringLiteral(types.DateString(now)))
+		infoDict.Update("ModDate", types.StringLiteral(types.DateString(now)))
+
+		metadata := types.StreamDict{
+			Dict: types.Dict{
+				"Type":    types.Name("Metadata"),
+				"Subtype": types.Name("XML"),
+			},
+			// PDF/A doesn't allow the metadata stream to be compressed.
+			Content: []byte(xmpPacket(info, "pdfcpu "+model.VersionStr, now, pdfa)),
+		}
+		if err := metadata.Encode(); err != nil {
+			return err
+		}
+		metadataRef, err := ctx.IndRefForNewObject(metadata)
+		if err != nil {
+			return err
+		}
+		rootDict, err := ctx.Catalog()
+		if err != nil {
+			return err
+		}
+		rootDict.Update("Metadata", *metadataRef)
+		return nil
+	}
+}
+
+// Returns the XMP packet for the given document information values.
+func xmpPacket(info map[string]string, producer string, date time.Time, pdfa bool) string {
+	esc := func(str string) string {
+		var builder strings.Builder
+		xml.EscapeText(&builder, []byte(str))
+		return builder.String()
+	}
+	xmpDate := date.Format("2006-01-02T15:04:05-07:00")
+
+	var builder strings.Builder
+	builder.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	builder.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	builder.WriteString("<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	builder.WriteString("<rdf:Description rdf:about=\"\"\n")
+	builder.WriteString("  xmlns:pdfaid=\"http://www.aiim.org/pdfa/ns/id/\"\n")
+	builder.WriteString("  xmlns:dc=\"http://purl.org/dc/elements/1.1/\"\n")
+	builder.WriteString("  xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\"\n")
+	builder.WriteString("  xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\">\n")
+	if pdfa {
+		builder.WriteString("<pdfaid:part>3</pdfaid:part>\n")
+		builder.WriteString("<pdfaid:conformance>B</pdfaid:conformance>\n")
+	}
+	builder.WriteString("<dc:format>application/pdf</dc:format>\n")
+	if title, ok := info["Title"]; ok {
+		fmt.Fprintf(&builder, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n", esc(title))
+	}
+	if author, ok := info["Author"]; ok {
+		fmt.Fprintf(&builder, "<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n", esc(author))
+	}
+	if subject, ok := info["Subject"]; ok {
+		fmt.Fprintf(&builder, "<dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>\n", esc(subject))
+	}
+	if keywords, ok := info["Keywords"]; ok {
+		fmt.Fprintf(&builder, "<pdf:Keywords>%s</pdf:Keywords>\n", esc(keywords))
+	}
+	fmt.Fprintf(&builder, "<pdf:Producer>%s</pdf:Producer>\n", esc(producer))
+	if creator, ok := info["Creator"]; ok {
+		fmt.Fprintf(&builder, "<xmp:CreatorTool>%s</xmp:CreatorTool>\n", esc(creator))
+	}
+	fmt.Fprintf(&builder, "<xmp:CreateDate>%s</xmp:CreateDate>\n", xmpDate)
+	fmt.Fprintf(&builder, "<xmp:ModifyDate>%s</xmp:ModifyDate>\n", xmpDate)
+	fmt.Fprintf(&builder, "<xmp:MetadataDate>%s</xmp:MetadataDate>\n", xmpDate)
+	builder.WriteString("</rdf:Description>\n</rdf:RDF>\n</x:xmpmeta>\n")
+	builder.WriteString("<?xpacket end=\"w\"?>")
+	return builder.String()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+func writeTestPDF(t *testing.T, created time.Time) string {
+	t.Helper()
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetCreationDate(created)
+	pdf.SetModificationDate(created)
+	pdf.AddPage()
+	path := filepath.Join(t.TempDir(), "report.pdf")
+	if err := pdf.OutputFileAndClose(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPostProcessPDFKeepsMode(t *testing.T) {
+	path := writeTestPDF(t, time.Now())
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := PostProcessPDF(path, SetCustomInfo(map[string]string{"Period": "2023"})); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("mode %s, expected %s", info.Mode().Perm(), os.FileMode(0640))
+	}
+}
+
+// Value of the first match of the pattern in the written file.
+func findInFile(t *testing.T, path, pattern string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := regexp.MustCompile(pattern).FindSubmatch(data)
+	if match == nil {
+		t.Fatalf("%s not found in %s", pattern, path)
+	}
+	return string(match[1])
+}
+
+// Dates of the document info of the last revision of the file.
+func readInfoDates(t *testing.T, path string) map[string]string {
+	t.Helper()
+	ctx, err := api.ReadContextFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dates, err := infoDates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dates
+}
+
+func TestPostProcessPDFKeepsDates(t *testing.T) {
+	created := time.Date(2023, 3, 14, 9, 26, 53, 0, time.UTC)
+	path := writeTestPDF(t, created)
+	if err := PostProcessPDF(path, SetCustomInfo(map[string]string{"Period": "2023"})); err != nil {
+		t.Fatal(err)
+	}
+	dates := readInfoDates(t, path)
+	for _, key := range INFO_DATE_KEYS {
+		date, ok := types.DateTime(dates[key], true)
+		if !ok || !date.Equal(created) {
+			t.Errorf("%s is %s, expected %s", key, date, created)
+		}
+	}
+}
+
+func TestAddXMPMetadataDates(t *testing.T) {
+	path := writeTestPDF(t, time.Date(2023, 3, 14, 9, 26, 53, 0, time.UTC))
+	if err := PostProcessPDF(path, AddXMPMetadata(false)); err != nil {
+		t.Fatal(err)
+	}
+	xmpDate, err := time.Parse(time.RFC3339, findInFile(t, path, `<xmp:CreateDate>([^<]*)<`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dates := readInfoDates(t, path)
+	for _, key := range INFO_DATE_KEYS {
+		date, ok := types.DateTime(dates[key], true)
+		if !ok || !date.Equal(xmpDate) {
+			t.Errorf("%s is %s, XMP metadata has %s", key, date, xmpDate)
+		}
+	}
+}
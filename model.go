@@ -7,6 +7,7 @@ import (
 	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,20 +19,6 @@ const DATE_FORMAT = "02.01.2006"
 const TIME_FORMAT = "15:04:05"
 const DATE_TIME_FORMAT = "02.01.06 15:04:05"
 
-var CURRENCY_SYMBOLS = map[string]string{
-	"EUR": "€",
-	"USD": "$",
-}
-
-// Get the currency symbol for the given currency code.
-func getCurrencySymbol(currency string) string {
-	symbol, ok := CURRENCY_SYMBOLS[currency]
-	if !ok {
-		return currency
-	}
-	return symbol
-}
-
 func resolveRelativePath(basePath, path string) (string, error) {
 	fullPath := filepath.Join(filepath.Dir(basePath), path)
 	return filepath.Abs(fullPath)
@@ -41,19 +28,39 @@ func resolveRelativePath(basePath, path string) (string, error) {
 type Dossier struct {
 	JournalEntries     Documents
 	AccountingFilePath string
-	BaseCurrency       string
-	CompanyName        string
-	Street             string
-	ZIPCode            string
-	Place              string
-	DateLastSaved      time.Time
-	TimeLastSaved      time.Time
-	OpeningDate        time.Time
-	ClosureDate        time.Time
+	// ISO 4217 code of the base currency.
+	BaseCurrency  string
+	CompanyName   string
+	Street        string
+	ZIPCode       string
+	Place         string
+	DateLastSaved time.Time
+	TimeLastSaved time.Time
+	OpeningDate   time.Time
+	ClosureDate   time.Time
+	// Type of the accounting file, detected from the file info or the
+	// journal columns.
+	FileType FileType
+	// Banana XML export the dossier was read from.
+	SourcePath   string
+	SourceSHA256 string
+	// Chart of accounts by account number.
+	Accounts map[string]Account
+	// Categories of cash basis files by code and descriptions of their
+	// groups by group code.
+	Categories     map[string]Category
+	CategoryGroups map[string]string
+	// Columns of the journal table.
+	JournalFields []Field
+	// Rates of the exchange rates table of multi-currency files.
+	ExchangeRates ExchangeRates
+	// All bookings of the journal including those without a linked
+	// receipt, used for the account statements.
+	bookings Transactions
 }
 
 func DossierFromXML(path string) (*Dossier, error) {
-	ac, err := AC2FromFile(path)
+	ac, err := AC2FromFile(path, AC2_TABLES...)
 	if err != nil {
 		return nil, err
 	}
@@ -68,24 +75,66 @@ func DossierFromXML(path string) (*Dossier, error) {
 		return nil, err
 	}
 
+	sourceHash, err := fileSHA256(path)
+	if err != nil {
+		return nil, err
+	}
+
 	journal := JournalFromTable(*journalTable)
 	entries := EntriesFromJournal(journal, fileInfoTable.GuardedValueById("FileName"))
 
+	// A file without accounts table can still be reported, the accounts
+	// just aren't resolved.
+	accounts := map[string]Account{}
+	if accountsTable, err := ac.TableById("Accounts"); err == nil {
+		accounts = AccountsFromTable(*accountsTable)
+	} else {
+		fmt.Println(err)
+	}
+	// Only cash basis files have categories.
+	categories, categoryGroups := map[string]Category{}, map[string]string{}
+	if categoriesTable, err := ac.TableById("Categories"); err == nil {
+		categories, categoryGroups = CategoriesFromTable(*categoriesTable)
+	}
+	// Only multi-currency files have exchange rates.
+	exchangeRates := ExchangeRates{}
+	if exchangeRatesTable, err := ac.TableById("ExchangeRates"); err == nil {
+		exchangeRates = ExchangeRatesFromTable(*exchangeRatesTable)
+	}
+
 	return &Dossier{
 		JournalEntries:     entries,
 		AccountingFilePath: fileInfoTable.GuardedValueById("FileName"),
-		BaseCurrency:       getCurrencySymbol(fileInfoTable.GuardedValueById("BasicCurrency")),
+		BaseCurrency:       normalizeCurrencyCode(fileInfoTable.GuardedValueById("BasicCurrency")),
 		CompanyName:        fileInfoTable.GuardedValueById("Company"),
 		Street:             fileInfoTable.GuardedValueById("Address1"),
 		ZIPCode:            fileInfoTable.GuardedValueById("Zip"),
 		Place:              fileInfoTable.GuardedValueById("City"),
+		FileType:           FileTypeFromTables(*fileInfoTable, *journalTable),
 		DateLastSaved:      fileInfoTable.GuardedDateById("DateLastSaved"),
 		TimeLastSaved:      fileInfoTable.GuardedTimeById("TimeLastSaved"),
 		OpeningDate:        fileInfoTable.GuardedDateById("OpeningDate"),
 		ClosureDate:        fileInfoTable.GuardedDateById("ClosureDate"),
+		SourcePath:         path,
+		SourceSHA256:       sourceHash,
+		Accounts:           accounts,
+		Categories:         categories,
+		CategoryGroups:     categoryGroups,
+		JournalFields:      journalTable.FieldList,
+		ExchangeRates:      exchangeRates,
+		bookings:           BookingsFromTable(*journalTable),
 	}, nil
 }
 
+// SetCashBasisAccounting forces cash basis accounting for a file detected as
+// double-entry file, with a warning as the columns probably don't match.
+func (d *Dossier) SetCashBasisAccounting(forced bool) {
+	if forced && !d.FileType.IsCashBasis() {
+		fmt.Printf("%s: cash basis accounting was requested but the file is a double-entry file\n", d.SourcePath)
+		d.FileType = d.FileType.asCashBasis()
+	}
+}
+
 func (d Dossier) ToJSON(path string) error {
 	data, err := json.MarshalIndent(d, "", "  ")
 	if err != nil {
@@ -94,6 +143,11 @@ func (d Dossier) ToJSON(path string) error {
 	return os.WriteFile(path, data, 0644)
 }
 
+// Base currency of the accounting file.
+func (d Dossier) Currency() Currency {
+	return LookupCurrency(d.BaseCurrency)
+}
+
 func (d Dossier) ResolveRelativePath(path string) (string, error) {
 	return resolveRelativePath(d.AccountingFilePath, path)
 }
@@ -163,9 +217,37 @@ type Document struct {
 	AbsolutePath string
 	IsValidFile  bool
 	PageCount    int
+	// Visible size and rotation of each page of a PDF receipt.
+	Pages        []ReceiptPage
 	FileError    error
 	FileUUID     string
 	Transactions Transactions
+	Verification *Verification
+	// Swiss QR-bill found on the receipt.
+	QRBill           *QRBill
+	QRBillMismatches []string
+	// Structured invoice data, either embedded into the PDF (Factur-X,
+	// ZUGFeRD) or as a bare XML file (XRechnung, UBL).
+	EInvoice     *EInvoice
+	IsXMLInvoice bool
+	// Reasons why the receipt prevents a PDF/A conformant report.
+	PDFAIssues []string
+	// Top-level outline entry the document belongs to, none if empty.
+	OutlineGroup string
+	// Outline entries below the document, one per doc number.
+	Outline []OutlineEntry
+	// Documents with the same doc number.
+	Related []RelatedDocument
+	// Link to the original receipt on disk, empty if disabled.
+	FileURL string
+	// Content of the QR code in the header.
+	QRPayload string
+	// Set if the receipt is a statement of an account.
+	Statement *Statement
+	// Accounts and categories booked by the transactions, in order of
+	// appearance.
+	Accounts   []Account
+	Categories []Category
 }
 
 func NewDocument(accountingFilePath, path string) Document {
@@ -188,16 +270,40 @@ func NewDocument(accountingFilePath, path string) Document {
 		return rsl
 	}
 
+	if strings.EqualFold(filepath.Ext(rsl.AbsolutePath), ".xml") {
+		rsl.IsXMLInvoice = true
+		rsl.FileUUID = strings.TrimSuffix(rsl.FileUUID, ".pdf") + ".xml"
+		rsl.PageCount = 1
+		data, err := os.ReadFile(rsl.AbsolutePath)
+		if err == nil {
+			rsl.EInvoice, err = ParseEInvoice(data)
+		}
+		if err != nil {
+			rsl.FileError = fmt.Errorf("failed to read e-invoice: %w", err)
+		}
+		return rsl
+	}
+
 	rsl.PageCount, err = GetPDFPageCount(rsl.AbsolutePath)
 	if err != nil {
 		rsl.FileError = err
 		return rsl
 	}
+	rsl.Pages, err = GetPDFPages(rsl.AbsolutePath)
+	if err != nil {
+		fmt.Printf("%s: failed to read page sizes: %s\n", path, err)
+	}
+
+	// A broken embedded invoice shouldn't prevent showing the PDF itself.
+	rsl.EInvoice, err = EInvoiceFromPDF(rsl.AbsolutePath)
+	if err != nil {
+		fmt.Printf("%s: failed to read embedded e-invoice: %s\n", path, err)
+	}
 
 	return rsl
 }
 
-func (d Document) IdentStringList() string {
+func (d Document) Idents() []string {
 	rsl := []string{}
 	for _, transaction := range d.Transactions {
 		add := true
@@ -211,7 +317,11 @@ func (d Document) IdentStringList() string {
 			rsl = append(rsl, transaction.Ident)
 		}
 	}
-	return strings.Join(rsl, ", ")
+	return rsl
+}
+
+func (d Document) IdentStringList() string {
+	return strings.Join(d.Idents(), ", ")
 }
 
 func (d Document) CreateSymlinkInFolder(folderPath string) error {
@@ -264,47 +374,69 @@ type Transaction struct {
 	Path             string
 	Description      string
 	AccountDebit     string
+	AccountDebitDes  string
 	AccountCredit    string
+	AccountCreditDes string
 	Amount           string // Always base currency.
 	Currency         string
 	AmountCurrency   string
 	ExchangeCurrency string
 	ExchangeRate     string
-	Cc3              string // Cost center 3
-	Cc3Des           string // Cost center 3 description
+	// Unit of the exchange rate (e.g. 100 for a rate per 100 units).
+	ExchangeMultiplier string
+	// Rate of the exchange rates table on the date of the booking and the
+	// deviation of the booked rate from it in percent, see
+	// CompareExchangeRates.
+	ReferenceRate   *ExchangeRate
+	RateDeviation   float64
+	IsRateDeviating bool
+	VatCode         string
+	Cc3             string // Cost center 3
+	Cc3Des          string // Cost center 3 description
 
 	// Cash basis accounting (EÜR) fields
 	Income      string
 	Expenses    string
 	Account     string
+	AccountDes  string
 	Category    string
 	CategoryDes string
+
+	// Values of all columns of the journal row by field ID.
+	Values map[string]string
 }
 
 func TransactionFromRow(row Row) Transaction {
 	return Transaction{
-		Unique:           row.Unique,
-		Section:          row.Section,
-		Ident:            row.Doc,
-		Date:             row.Date,
-		Path:             row.DocLink,
-		Description:      row.Description,
-		AccountDebit:     row.AccountDebit,
-		AccountCredit:    row.AccountCredit,
-		Amount:           row.Amount,
-		Currency:         row.Currency,
-		AmountCurrency:   row.AmountCurrency,
-		ExchangeCurrency: row.ExchangeCurrency,
-		ExchangeRate:     row.ExchangeRate,
-		Cc3:              row.Cc3,
-		Cc3Des:           row.Cc3Des,
+		Unique:             row.Unique,
+		Section:            row.Section,
+		Ident:              row.Doc,
+		Date:               row.Date,
+		Path:               row.DocLink,
+		Description:        row.Description,
+		AccountDebit:       row.AccountDebit,
+		AccountDebitDes:    row.AccountDebitDes,
+		AccountCredit:      row.AccountCredit,
+		AccountCreditDes:   row.AccountCreditDes,
+		Amount:             row.Amount,
+		Currency:           row.Currency,
+		AmountCurrency:     row.AmountCurrency,
+		ExchangeCurrency:   row.ExchangeCurrency,
+		ExchangeRate:       row.ExchangeRate,
+		ExchangeMultiplier: row.ExchangeMultiplier,
+		VatCode:            row.VatCode,
+		Cc3:                row.Cc3,
+		Cc3Des:             row.Cc3Des,
 
 		// Cash basis accounting (EÜR) fields
 		Income:      row.Income,
 		Expenses:    row.Expenses,
 		Account:     row.Account,
+		AccountDes:  row.AccountDes,
 		Category:    row.Category,
 		CategoryDes: row.CategoryDes,
+
+		Values: row.Values,
 	}
 }
 
@@ -338,18 +470,51 @@ func (t Transaction) GetAccountCredit(cashBasisAccounting bool) string {
 	return t.AccountCredit
 }
 
-func (t Transaction) GetAmount(cashBasisAccounting bool) string {
-	if cashBasisAccounting {
-		var rsl []string
-		if t.Income != "" {
-			rsl = append(rsl, t.Income)
-		}
-		if t.Expenses != "" {
-			rsl = append(rsl, t.Expenses)
-		}
-		return strings.Join(rsl, "/")
+// Formatted amount of the transaction table in the given base currency,
+// see BaseAmount.
+func (t Transaction) GetAmount(cashBasisAccounting bool, base Currency) string {
+	amount, err := t.BaseAmount(cashBasisAccounting)
+	if err != nil {
+		return UNKNOWN_STR
 	}
-	return t.AccountDebit
+	return base.FmtValue(amount)
+}
+
+// Signed amount in base currency. Double-entry bookings are positive, a
+// negative amount reverses a booking. In cash basis files income is positive
+// and expenses are negative, a refund (negative expense) is positive.
+func (t Transaction) BaseAmount(cashBasisAccounting bool) (float64, error) {
+	if !cashBasisAccounting {
+		return t.parseAmount(t.Amount)
+	}
+	income, err := t.parseAmount(t.Income)
+	if err != nil {
+		return 0, err
+	}
+	expenses, err := t.parseAmount(t.Expenses)
+	if err != nil {
+		return 0, err
+	}
+	return income - expenses, nil
+}
+
+// Amount in the currency of the booking (ExchangeCurrency) as booked, zero
+// for bookings without amount in foreign currency.
+func (t Transaction) CurrencyAmount() (float64, error) {
+	return t.parseAmount(t.AmountCurrency)
+}
+
+// Parses an amount column of the booking, empty columns are zero.
+func (t Transaction) parseAmount(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	rsl, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount '%s' of booking %s", value, t.Unique)
+	}
+	return rsl, nil
 }
 
 // To represent accounts payable (AP) and receivable (AR) transactions within the cash
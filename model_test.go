@@ -0,0 +1,118 @@
+package main
+
+import "testing"
+
+func TestTransactionAmounts(t *testing.T) {
+	chf := LookupCurrency("CHF")
+	tests := []struct {
+		name                string
+		row                 Row
+		cashBasisAccounting bool
+		amount              float64
+		fmt                 string
+		invalid             bool
+	}{
+		{
+			name:   "double-entry",
+			row:    Row{Unique: "1", AccountDebit: "6000", AccountCredit: "1020", Amount: "1500.00"},
+			amount: 1500,
+			fmt:    "1500.00",
+		},
+		{
+			name:   "double-entry reversed",
+			row:    Row{Unique: "2", AccountDebit: "6000", AccountCredit: "1020", Amount: "-250.5"},
+			amount: -250.5,
+			fmt:    "-250.50",
+		},
+		{
+			name:                "income",
+			row:                 Row{Unique: "3", Account: "1020", Category: "4000", Income: "1190.00"},
+			cashBasisAccounting: true,
+			amount:              1190,
+			fmt:                 "1190.00",
+		},
+		{
+			name:                "expense",
+			row:                 Row{Unique: "4", Account: "1020", Category: "6800", Expenses: "80.00"},
+			cashBasisAccounting: true,
+			amount:              -80,
+			fmt:                 "-80.00",
+		},
+		{
+			name:                "refund of an expense",
+			row:                 Row{Unique: "5", Account: "1020", Category: "6800", Expenses: "-20.00"},
+			cashBasisAccounting: true,
+			amount:              20,
+			fmt:                 "20.00",
+		},
+		{
+			name:    "invalid amount",
+			row:     Row{Unique: "6", AccountDebit: "6000", AccountCredit: "1020", Amount: "12,50"},
+			invalid: true,
+			fmt:     UNKNOWN_STR,
+		},
+		{
+			name:                "invalid expenses",
+			row:                 Row{Unique: "7", Account: "1020", Category: "6800", Expenses: "abc"},
+			cashBasisAccounting: true,
+			invalid:             true,
+			fmt:                 UNKNOWN_STR,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx := TransactionFromRow(test.row)
+			amount, err := tx.BaseAmount(test.cashBasisAccounting)
+			if test.invalid != (err != nil) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if amount != test.amount {
+				t.Errorf("amount %g, expected %g", amount, test.amount)
+			}
+			if formatted := tx.GetAmount(test.cashBasisAccounting, chf); formatted != test.fmt {
+				t.Errorf("formatted amount %s, expected %s", formatted, test.fmt)
+			}
+		})
+	}
+}
+
+func TestTransactionCurrencyAmount(t *testing.T) {
+	tests := []struct {
+		name    string
+		row     Row
+		amount  float64
+		invalid bool
+	}{
+		{
+			name:   "foreign currency",
+			row:    Row{Unique: "1", Amount: "98.00", AmountCurrency: "100.00", ExchangeCurrency: "EUR", ExchangeRate: "0.98"},
+			amount: 100,
+		},
+		{
+			name:   "reversed",
+			row:    Row{Unique: "2", Amount: "-98.00", AmountCurrency: "-100.00", ExchangeCurrency: "EUR", ExchangeRate: "0.98"},
+			amount: -100,
+		},
+		{
+			name:   "base currency",
+			row:    Row{Unique: "3", Amount: "98.00"},
+			amount: 0,
+		},
+		{
+			name:    "invalid",
+			row:     Row{Unique: "4", Amount: "98.00", AmountCurrency: "100 EUR", ExchangeCurrency: "EUR"},
+			invalid: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			amount, err := TransactionFromRow(test.row).CurrencyAmount()
+			if test.invalid != (err != nil) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if amount != test.amount {
+				t.Errorf("amount %g, expected %g", amount, test.amount)
+			}
+		})
+	}
+}
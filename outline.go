@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Modes of grouping the documents in the outline (bookmarks) of the report.
+const (
+	OUTLINE_GROUP_MONTH     = "month"
+	OUTLINE_GROUP_ACCOUNT   = "account"
+	OUTLINE_GROUP_DIRECTORY = "directory"
+	OUTLINE_GROUP_NONE      = "none"
+)
+
+// Groups of the documents without booking date or account, sorted after
+// all other groups.
+const (
+	OUTLINE_GROUP_NO_DATE    = "Ohne Datum"
+	OUTLINE_GROUP_NO_ACCOUNT = "Ohne Konto"
+)
+
+// GroupForOutline sets the outline entries of the documents and the group
+// according to the given mode. The documents are ordered by group so that
+// each group is one contiguous range of pages, within a group the order
+// stays the same.
+func (d *Dossier) GroupForOutline(mode string, cashBasisAccounting bool) error {
+	for i := range d.JournalEntries {
+		d.JournalEntries[i].Outline = d.JournalEntries[i].outlineEntries()
+		d.JournalEntries[i].OutlineGroup = ""
+	}
+	switch mode {
+	case OUTLINE_GROUP_MONTH, OUTLINE_GROUP_ACCOUNT, OUTLINE_GROUP_DIRECTORY:
+	case OUTLINE_GROUP_NONE, "":
+		return nil
+	default:
+		return fmt.Errorf(
+			"invalid outline group '%s', available groups: %s, %s, %s, %s",
+			mode, OUTLINE_GROUP_MONTH, OUTLINE_GROUP_ACCOUNT, OUTLINE_GROUP_DIRECTORY, OUTLINE_GROUP_NONE,
+		)
+	}
+	for i := range d.JournalEntries {
+		doc := &d.JournalEntries[i]
+		doc.OutlineGroup = doc.outlineGroup(mode, cashBasisAccounting)
+	}
+	sort.SliceStable(d.JournalEntries, func(i, j int) bool {
+		a, b := d.JournalEntries[i].OutlineGroup, d.JournalEntries[j].OutlineGroup
+		if isFallbackOutlineGroup(a) != isFallbackOutlineGroup(b) {
+			return isFallbackOutlineGroup(b)
+		}
+		return a < b
+	})
+	return nil
+}
+
+func isFallbackOutlineGroup(group string) bool {
+	return group == OUTLINE_GROUP_NO_DATE || group == OUTLINE_GROUP_NO_ACCOUNT
+}
+
+// Group of the document, the month (YYYY-MM) of the first booking, the
+// account of the first transaction or the directory of the receipt. Empty
+// if the documents aren't grouped.
+func (d Document) outlineGroup(mode string, cashBasisAccounting bool) string {
+	switch mode {
+	case OUTLINE_GROUP_MONTH:
+		for _, tx := range d.Transactions {
+			if date, err := tx.ParsedDate(); err == nil {
+				return date.Format("2006-01")
+			}
+		}
+		return OUTLINE_GROUP_NO_DATE
+	case OUTLINE_GROUP_ACCOUNT:
+		for _, tx := range d.Transactions {
+			if account := tx.GetAccountDebit(cashBasisAccounting); account != "" {
+				return account
+			}
+			if account := tx.GetAccountCredit(cashBasisAccounting); account != "" {
+				return account
+			}
+		}
+		return OUTLINE_GROUP_NO_ACCOUNT
+	case OUTLINE_GROUP_DIRECTORY:
+		return filepath.Dir(d.Path)
+	}
+	return ""
+}
+
+// Outline entry of one doc number (Beleg) of a document.
+type OutlineEntry struct {
+	Ident string
+	Title string
+}
+
+// Returns one outline entry per doc number of the document in the order of
+// the transactions.
+func (d Document) outlineEntries() []OutlineEntry {
+	rsl := []OutlineEntry{}
+	seen := map[string]bool{}
+	for _, tx := range d.Transactions {
+		if seen[tx.Ident] {
+			continue
+		}
+		seen[tx.Ident] = true
+		title := []string{}
+		if tx.Ident != "" {
+			title = append(title, tx.Ident)
+		}
+		title = append(title, tx.FmtDate())
+		if description := tx.FmtDescription(); description != "" {
+			title = append(title, description)
+		}
+		rsl = append(rsl, OutlineEntry{
+			Ident: tx.Ident,
+			Title: strings.Join(title, " · "),
+		})
+	}
+	return rsl
+}
@@ -7,6 +7,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,6 +31,13 @@ var literataMedium []byte
 //go:embed static/sad-document.png
 var sadDocument []byte
 
+// Prefixed to amounts which couldn't be found on the receipt.
+const VERIFICATION_MARKER = "?"
+
+// Marks foreign currency bookings whose rate deviates from the exchange
+// rates table.
+const RATE_DEVIATION_MARKER = "!"
+
 type DebugColor int
 
 const (
@@ -56,31 +65,82 @@ func (c DebugColor) GetValues() (r, g, b int) {
 	}
 }
 
+// Options of the fpdf engine.
+type PDFOptions struct {
+	// Decides the columns of the transaction table.
+	FileType       FileType
+	DebugCells     bool
+	DebugLines     bool
+	StepEmbedError bool
+	// Embed the original receipt files as file attachments.
+	AttachOriginals bool
+	// Start with a linked table of contents.
+	TableOfContents bool
+	// Cut long descriptions instead of wrapping them.
+	TruncateDescriptions bool
+	// Decimal places of the exchange rates.
+	ExchangeRateDecimals int
+	// Deviation in percent from the exchange rates table above which
+	// booked rates are marked, see CompareExchangeRates.
+	MaxRateDeviation float64
+	// Journal columns shown in the transaction table in addition to the
+	// default ones.
+	Columns []Field
+	// Print the notes of the bookings which don't fit into the table as
+	// footnotes below it.
+	NotesFootnotes bool
+	Page           PageSetup
+	Fonts          Fonts
+}
+
 type PDF struct {
 	*fpdf.Fpdf
-	debugCells          bool
-	debugLines          bool
-	stepEmbedError      bool
-	sadDocumentOptions  fpdf.ImageOptions
-	CashBasisAccounting bool
-	PageWidth           float64
-	PageHeight          float64
-	AreaWidth           float64
-	AreaHeight          float64
-	FontFamily          string
-	LeftMargin          float64
-	TopMargin           float64
-	RightMargin         float64
-	BottomMargin        float64
-}
-
-func NewPDF(cashBasisAccounting bool, debugCells, debugLines, stepEmbedError bool) PDF {
-	fontName := "Literata"
-	pdf := fpdf.New("P", "mm", "A4", "")
-	pdf.SetAutoPageBreak(false, 10)
-	pdf.AddUTF8FontFromBytes(fontName, "", literataRegular)
-	pdf.AddUTF8FontFromBytes(fontName, "I", literataItalic)
-	pdf.AddUTF8FontFromBytes(fontName, "B", literataMedium)
+	debugCells           bool
+	debugLines           bool
+	stepEmbedError       bool
+	attachOriginals      bool
+	originals            *[]OriginalAttachment
+	pageLabels           *[]PageLabel
+	lastOutlineGroup     *string
+	tableOfContents      bool
+	truncateDescription  bool
+	exchangeRateDecimals int
+	maxRateDeviation     float64
+	extraColumns         []Field
+	notesFootnotes       bool
+	docLinks             *map[string]int
+	fileType             FileType
+	columns              tableColumns
+	fonts                Fonts
+	fontStyle            *string
+	sadDocumentOptions   fpdf.ImageOptions
+	CashBasisAccounting  bool
+	PageWidth            float64
+	PageHeight           float64
+	AreaWidth            float64
+	AreaHeight           float64
+	FontFamily           string
+	LeftMargin           float64
+	TopMargin            float64
+	RightMargin          float64
+	BottomMargin         float64
+}
+
+func NewPDF(options PDFOptions) PDF {
+	fonts := options.Fonts
+	margins := options.Page.Margins
+	pdf := fpdf.New(options.Page.Orientation(), "mm", options.Page.Size, "")
+	pdf.SetMargins(margins.Left, margins.Top, margins.Right)
+	pdf.SetAutoPageBreak(false, margins.Bottom)
+	pdf.AddUTF8FontFromBytes(fonts.Regular.Family, "", fonts.Regular.Data)
+	pdf.AddUTF8FontFromBytes(fonts.Regular.Family, "I", fonts.Italic.Data)
+	pdf.AddUTF8FontFromBytes(fonts.Regular.Family, "B", fonts.Bold.Data)
+	for i, fallback := range fonts.Fallbacks {
+		// Fallback fonts have no styles of their own.
+		for _, style := range []string{"", "I", "B"} {
+			pdf.AddUTF8FontFromBytes(fallbackFamily(i), style, fallback.Data)
+		}
+	}
 
 	imgRd := bytes.NewReader(sadDocument)
 	sadDocumentOpt := fpdf.ImageOptions{
@@ -91,25 +151,47 @@ func NewPDF(cashBasisAccounting bool, debugCells, debugLines, stepEmbedError boo
 	pageWidth, pageHeight := pdf.GetPageSize()
 	lm, tm, rm, bm := pdf.GetMargins()
 	return PDF{
-		Fpdf:                pdf,
-		debugCells:          debugCells,
-		debugLines:          debugLines,
-		stepEmbedError:      stepEmbedError,
-		sadDocumentOptions:  sadDocumentOpt,
-		CashBasisAccounting: cashBasisAccounting,
-		PageWidth:           pageWidth,
-		PageHeight:          pageHeight,
-		AreaWidth:           pageWidth - lm - rm,
-		AreaHeight:          pageHeight - tm - bm,
-		FontFamily:          fontName,
-		LeftMargin:          lm,
-		TopMargin:           tm,
-		RightMargin:         rm,
-		BottomMargin:        bm,
+		Fpdf:                 pdf,
+		debugCells:           options.DebugCells,
+		debugLines:           options.DebugLines,
+		stepEmbedError:       options.StepEmbedError,
+		attachOriginals:      options.AttachOriginals,
+		originals:            &[]OriginalAttachment{},
+		pageLabels:           &[]PageLabel{},
+		lastOutlineGroup:     new(string),
+		tableOfContents:      options.TableOfContents,
+		truncateDescription:  options.TruncateDescriptions,
+		exchangeRateDecimals: options.ExchangeRateDecimals,
+		maxRateDeviation:     options.MaxRateDeviation,
+		extraColumns:         options.Columns,
+		notesFootnotes:       options.NotesFootnotes,
+		docLinks:             &map[string]int{},
+		fileType:             options.FileType,
+		columns:              newTableColumns(pageWidth-lm-rm, options.FileType, len(options.Columns)),
+		fonts:                fonts,
+		fontStyle:            new(string),
+		sadDocumentOptions:   sadDocumentOpt,
+		CashBasisAccounting:  options.FileType.IsCashBasis(),
+		PageWidth:            pageWidth,
+		PageHeight:           pageHeight,
+		AreaWidth:            pageWidth - lm - rm,
+		AreaHeight:           pageHeight - tm - bm,
+		FontFamily:           fonts.Regular.Family,
+		LeftMargin:           lm,
+		TopMargin:            tm,
+		RightMargin:          rm,
+		BottomMargin:         bm,
 	}
 }
 
 func (pdf PDF) Build(dossier *Dossier) {
+	pdf.setDocumentInfo(dossier.DocumentInfo())
+	if pdf.tableOfContents {
+		*pdf.pageLabels = append(*pdf.pageLabels, PageLabel{Page: 1, Style: "r"})
+		pdf.addTableOfContents(*dossier)
+	}
+	// Labels follow the report page counter of the footer.
+	*pdf.pageLabels = append(*pdf.pageLabels, PageLabel{Page: pdf.PageNo() + 1, Style: "D"})
 	runningPageCount := 1
 	for i, doc := range dossier.JournalEntries {
 		// FOR DEBUG
@@ -118,35 +200,215 @@ func (pdf PDF) Build(dossier *Dossier) {
 		}
 		embedPDFPageCount := 1
 		for page := 1; page <= embedPDFPageCount; page++ {
-			embedPDFPageCount = pdf.addDocument(*dossier, doc, page, runningPageCount)
-			runningPageCount++
+			embedPDFPageCount = pdf.addDocument(*dossier, doc, page, &runningPageCount)
 		}
 		// FOR DEBUG
 		if i == 10 {
 			// return
 		}
 	}
+	if pdf.CashBasisAccounting {
+		*pdf.pageLabels = append(*pdf.pageLabels, PageLabel{Page: pdf.PageNo() + 1, Prefix: "EÜR-", Style: "D"})
+		pdf.addCashBasisSummary(*dossier)
+	}
+	if dossier.IsVerified() {
+		*pdf.pageLabels = append(*pdf.pageLabels, PageLabel{Page: pdf.PageNo() + 1, Prefix: "V-", Style: "D"})
+		pdf.addVerificationSummary(*dossier)
+	}
+	if deviations := dossier.RateDeviations(); len(deviations) != 0 {
+		*pdf.pageLabels = append(*pdf.pageLabels, PageLabel{Page: pdf.PageNo() + 1, Prefix: "W-", Style: "D"})
+		pdf.addRateDeviationSummary(deviations)
+	}
+}
+
+// Standard keys of the document information dictionary, see SetCustomInfo
+// for the others.
+func (pdf PDF) setDocumentInfo(info DocumentInfo) {
+	pdf.SetTitle(info.Title, true)
+	pdf.SetAuthor(info.Author, true)
+	pdf.SetSubject(info.Subject, true)
+	pdf.SetKeywords(info.Keywords, true)
+	pdf.SetCreator(info.Creator, true)
+	pdf.SetCreationDate(time.Now())
+}
+
+// Internal link to the first page of the document with the given path. The
+// target is set when the document is added.
+func (pdf PDF) docLink(path string) int {
+	link, ok := (*pdf.docLinks)[path]
+	if !ok {
+		link = pdf.AddLink()
+		(*pdf.docLinks)[path] = link
+	}
+	return link
+}
+
+// Placeholder for the report page of a document link, replaced on output.
+func pageAlias(link int) string {
+	return fmt.Sprintf("{page-%d}", link)
+}
+
+// Lists all documents with their doc numbers and report page, grouped like
+// the outline. Each entry links to the first page of the document.
+func (pdf PDF) addTableOfContents(dossier Dossier) {
+	title := "Contents"
+	pdf.SetAutoPageBreak(true, pdf.BottomMargin)
+	defer pdf.SetAutoPageBreak(false, pdf.BottomMargin)
+	pdf.AddPage()
+	pdf.Bookmark(title, 0, -1)
+	pdf.TextCell(pdf.AreaWidth, 6.5, title, 1, "LT", 18, "B", 1.5, "", false)
+	pdf.Ln(3)
+
+	rowHeight := 4.5
+	pageWidth := 15.
+	identWidth := 60.
+	titleWidth := pdf.AreaWidth - identWidth - pageWidth
+	lastGroup := ""
+	pdf.SetCellMargin(1.5)
+	for _, doc := range dossier.JournalEntries {
+		if doc.OutlineGroup != "" && doc.OutlineGroup != lastGroup {
+			pdf.Ln(1.5)
+			pdf.SetFont(pdf.FontFamily, "B", 9)
+			pdf.CellFormat(pdf.AreaWidth, rowHeight+1, doc.OutlineGroup, "", 1, "L", false, 0, "")
+			lastGroup = doc.OutlineGroup
+		}
+		link := pdf.docLink(doc.Path)
+		pdf.SetFont(pdf.FontFamily, "", pdf.fitTextToWidth(doc.Title(), titleWidth, 1.5, 8, ""))
+		pdf.CellFormat(titleWidth, rowHeight, doc.Title(), "", 0, "L", false, link, "")
+		pdf.SetFont(pdf.FontFamily, "", pdf.fitTextToWidth(doc.IdentStringList(), identWidth, 1.5, 8, ""))
+		pdf.CellFormat(identWidth, rowHeight, doc.IdentStringList(), "", 0, "L", false, link, "")
+		pdf.SetFont(pdf.FontFamily, "", 8)
+		// The alias is replaced by a shorter number, right alignment would
+		// be off.
+		pdf.CellFormat(pageWidth, rowHeight, pageAlias(link), "", 1, "L", false, link, "")
+	}
+	pdf.SetCellMargin(0)
+}
+
+// Page labels of the built report.
+func (pdf PDF) PageLabels() []PageLabel {
+	return *pdf.pageLabels
 }
 
-func (pdf PDF) addDocument(dossier Dossier, doc Document, embedPageNr, reportPageCount int) (pageCount int) {
-	// If not -1 there is another page from this receipt.
+// Share of the area kept for the receipt below the transaction table. If
+// the table needs more space it gets pages of its own and the receipt
+// follows on the next page.
+const MIN_RECEIPT_SHARE = 1. / 3
+
+// Adds the report page(s) of the given receipt page. The report page counter
+// is advanced by the number of added pages.
+func (pdf PDF) addDocument(dossier Dossier, doc Document, embedPageNr int, reportPageCount *int) (pageCount int) {
+	footerHeight := 10.
+	rowHeight := 5.
 	pdf.AddPage()
+	if embedPageNr == 1 {
+		link := pdf.docLink(doc.Path)
+		pdf.SetLink(link, 0, -1)
+		if pdf.tableOfContents {
+			pdf.RegisterAlias(pageAlias(link), strconv.Itoa(*reportPageCount))
+		}
+	}
 	pdf.Rect(pdf.LeftMargin, pdf.TopMargin, pdf.AreaWidth, pdf.AreaHeight, "D")
-	pdf.addHeader(doc, embedPageNr)
+	pdf.addHeader(doc, embedPageNr == 1)
+	if embedPageNr == 1 && pdf.attachOriginals {
+		pdf.attachOriginal(doc)
+	}
 
 	if embedPageNr == 1 {
-		pdf.addTableHeader(5)
-		pdf.addTableRows(doc.Transactions, 5, dossier.BaseCurrency)
+		areaBottom := pdf.TopMargin + pdf.AreaHeight - footerHeight
+		maxY := areaBottom - pdf.AreaHeight*MIN_RECEIPT_SHARE
+		overflow := false
+		// Finishes the current page and continues on a new one.
+		newPage := func() {
+			pdf.addFooter(dossier, doc, footerHeight, 0, doc.PageCount, *reportPageCount)
+			*reportPageCount++
+			pdf.AddPage()
+			pdf.Rect(pdf.LeftMargin, pdf.TopMargin, pdf.AreaWidth, pdf.AreaHeight, "D")
+			pdf.addHeader(doc, false)
+		}
+		tableHeader := pdf.addTableHeader
+		if doc.Statement != nil {
+			tableHeader = pdf.addStatementHeader
+		}
+		// The table continues on pages of its own.
+		pageBreak := func() float64 {
+			overflow = true
+			newPage()
+			tableHeader(rowHeight)
+			return areaBottom
+		}
+		tableHeader(rowHeight)
+		notes := []footnote{}
+		if doc.Statement != nil {
+			maxY = pdf.addStatementRows(doc, rowHeight, maxY, pageBreak)
+		} else {
+			notes = pdf.footnotes(doc)
+			maxY = pdf.addTableRows(doc, notes, rowHeight, dossier.Currency(), maxY, pageBreak)
+		}
+		infoHeight := float64(infoRowCount(doc, dossier.Currency()))*rowHeight + pdf.footnotesHeight(notes, rowHeight)
+		if pdf.GetY()+infoHeight > areaBottom {
+			pageBreak()
+		}
+		if doc.Transactions.HasForeignCurrency(dossier.Currency()) {
+			pdf.addCurrencyTotals(doc, dossier.Currency(), rowHeight)
+		}
+		if doc.QRBill != nil {
+			pdf.addQRBillInfo(doc, rowHeight)
+		}
+		if doc.EInvoice != nil && !doc.IsXMLInvoice {
+			pdf.addEInvoiceInfo(doc, rowHeight)
+		}
+		if len(doc.Accounts) != 0 {
+			pdf.addLegend("Konten", accountLegend(doc), rowHeight)
+		}
+		if len(doc.Categories) != 0 {
+			pdf.addLegend("Kategorien", categoryLegend(doc), rowHeight)
+		}
+		if len(doc.Related) != 0 {
+			pdf.addRelatedDocuments(doc, rowHeight)
+		}
+		if len(notes) != 0 {
+			pdf.addFootnotes(notes, rowHeight)
+		}
 		pdf.HLine(0, false, ColorMagenta)
+		if overflow || pdf.GetY() > areaBottom-pdf.AreaHeight*MIN_RECEIPT_SHARE {
+			newPage()
+			pdf.Bookmark("Receipt", outlineLevel(doc)+1, -1)
+		}
 	}
-	pageCount = pdf.embedDocument(dossier, doc, embedPageNr, 10)
-	pdf.addFooter(dossier, doc, 10, embedPageNr, pageCount, reportPageCount)
+	pageCount = pdf.embedDocument(dossier, doc, embedPageNr, footerHeight)
+	pdf.addFooter(dossier, doc, footerHeight, embedPageNr, pageCount, *reportPageCount)
+	*reportPageCount++
 	return pageCount
 }
 
-func (pdf PDF) addHeader(doc Document, embedPageNr int) {
-	title := strings.TrimSuffix(filepath.Base(doc.Path), filepath.Ext(doc.Path))
-	if embedPageNr != 1 {
+// Number of rows shown below the transactions.
+func infoRowCount(doc Document, base Currency) int {
+	rsl := 0
+	if doc.Transactions.HasForeignCurrency(base) {
+		rsl++
+	}
+	if doc.QRBill != nil {
+		rsl += 1 + len(doc.QRBillMismatches)
+	}
+	if doc.EInvoice != nil && !doc.IsXMLInvoice {
+		rsl++
+	}
+	if len(doc.Accounts) != 0 {
+		rsl++
+	}
+	if len(doc.Categories) != 0 {
+		rsl++
+	}
+	if len(doc.Related) != 0 {
+		rsl++
+	}
+	return rsl
+}
+
+func (pdf PDF) addHeader(doc Document, isFirstPage bool) {
+	title := doc.Title()
+	if !isFirstPage {
 		title = fmt.Sprintf("→ %s (cont.)", title)
 	}
 	description1 := fmt.Sprintf("%s — ", doc.Path)
@@ -156,16 +418,25 @@ func (pdf PDF) addHeader(doc Document, embedPageNr int) {
 	textBlockWidth := pdf.AreaWidth - qrBlockDimensions
 	qrBlockX := pdf.LeftMargin + textBlockWidth
 
-	pdf.Bookmark(title, 0, -1)
+	if isFirstPage {
+		pdf.addBookmarks(doc, title)
+	}
 
 	pdf.Ln(1.5)
-	headingHeight, _ := pdf.TextCell(textBlockWidth, 6.5, title, 0, "LT", 18, "B", 1.5, "", true)
+	headingHeight, _ := pdf.TextCell(textBlockWidth, 6.5, title, 0, "LT", 1.8*pdf.fonts.Sizes.Header, "B", 1.5, "", true)
 	pdf.Ln((headingHeight - 1.5) * 1.3)
-	_, descFontSize := pdf.TextCell(textBlockWidth, 5, description1, -1, "LT", 10, "", 1.5, description, true)
+	_, descFontSize := pdf.TextCell(textBlockWidth, 5, description1, -1, "LT", pdf.fonts.Sizes.Header, "", 1.5, description, true)
 	pdf.TextCell(pdf.GetStringWidth(description2), 5, description2, 0, "LT", descFontSize, "B", 0, description2, true)
+	if doc.FileURL != "" {
+		pdf.LinkString(pdf.LeftMargin, pdf.TopMargin, textBlockWidth, qrBlockDimensions, doc.FileURL)
+	}
 
 	pdf.Line(qrBlockX, pdf.TopMargin, qrBlockX, pdf.TopMargin+qrBlockDimensions)
-	qrCode, err := qr.Encode(doc.Path, qr.L, qr.Unicode)
+	payload := doc.QRPayload
+	if payload == "" {
+		payload = doc.Path
+	}
+	qrCode, err := qr.Encode(payload, qr.L, qr.Unicode)
 	if err != nil {
 		panic(err)
 	}
@@ -185,74 +456,200 @@ func (pdf PDF) addHeader(doc Document, embedPageNr int) {
 	pdf.SetY(endOfHeaderY)
 }
 
-func (pdf PDF) addTableHeader(rowHeight float64) {
-	debit_header := "Soll"
-	credit_header := "Haben"
-	if pdf.CashBasisAccounting {
-		debit_header = "Konto"
-		credit_header = "Kategorie"
+// Outline of the first page of a document: the group (if it changed), the
+// document and its doc numbers. Continuation pages get no entry.
+func (pdf PDF) addBookmarks(doc Document, title string) {
+	if doc.OutlineGroup != "" && doc.OutlineGroup != *pdf.lastOutlineGroup {
+		pdf.Bookmark(doc.OutlineGroup, 0, -1)
+		*pdf.lastOutlineGroup = doc.OutlineGroup
+	}
+	pdf.Bookmark(title, outlineLevel(doc), -1)
+}
+
+// Outline level of the document entry, the entries of the doc numbers are
+// added by addTableRows at the row of their first transaction.
+func outlineLevel(doc Document) int {
+	if doc.OutlineGroup != "" {
+		return 1
 	}
+	return 0
+}
 
-	pdf.SetFont(pdf.FontFamily, "B", 7)
+// Marks the header of the current page as the place where the original
+// receipt file is attached. The file itself is embedded during
+// post-processing, see AttachOriginals.
+func (pdf PDF) attachOriginal(doc Document) {
+	if !doc.IsValidFile {
+		return
+	}
+	k := pdf.GetConversionRatio()
+	x, y := pdf.LeftMargin, pdf.TopMargin
+	w, h := pdf.AreaWidth-15, 15.
+	*pdf.originals = append(*pdf.originals, OriginalAttachment{
+		Page:        pdf.PageNo(),
+		Path:        doc.AbsolutePath,
+		FileName:    filepath.Base(doc.Path),
+		Description: fmt.Sprint("Original receipt: ", doc.Path),
+		Rect:        [4]float64{x * k, (pdf.PageHeight - y - h) * k, (x + w) * k, (pdf.PageHeight - y) * k},
+	})
+}
+
+// Original receipts attached so far.
+func (pdf PDF) Originals() []OriginalAttachment {
+	return *pdf.originals
+}
+
+func (pdf PDF) addTableHeader(rowHeight float64) {
+	debit_header, credit_header := pdf.fileType.AccountHeaders()
+
+	pdf.SetFont(pdf.FontFamily, "B", pdf.fonts.Sizes.Table)
 	pdf.SetCellMargin(1.5)
-	pdf.CellFormat(23, rowHeight, "Beleg", "", 0, "L", false, 0, "")
+	pdf.CellFormat(pdf.columns.Ident, rowHeight, "Beleg", "", 0, "L", false, 0, "")
 	pdf.SetCellMargin(0)
-	pdf.CellFormat(14, rowHeight, "Datum", "", 0, "L", false, 0, "")
-	pdf.CellFormat(103.49, rowHeight, "Beschreibung", "", 0, "L", false, 0, "")
-	pdf.CellFormat(14, rowHeight, debit_header, "", 0, "R", false, 0, "")
-	pdf.CellFormat(14, rowHeight, credit_header, "", 0, "R", false, 0, "")
-	pdf.CellFormat(20, rowHeight, "Betrag", "", 1, "R", false, 0, "")
+	pdf.CellFormat(pdf.columns.Date, rowHeight, "Datum", "", 0, "L", false, 0, "")
+	pdf.CellFormat(pdf.columns.Description, rowHeight, "Beschreibung", "", 0, "L", false, 0, "")
+	for _, field := range pdf.extraColumns {
+		pdf.TableCell(pdf.columns.Extra, rowHeight, field.Title(), "", 0, field.align())
+	}
+	pdf.CellFormat(pdf.columns.Debit, rowHeight, debit_header, "", 0, "R", false, 0, "")
+	pdf.CellFormat(pdf.columns.Credit, rowHeight, credit_header, "", 0, "R", false, 0, "")
+	if pdf.fileType.HasVAT() {
+		pdf.CellFormat(pdf.columns.Vat, rowHeight, "MwSt.", "", 0, "R", false, 0, "")
+	}
+	pdf.CellFormat(pdf.columns.Amount, rowHeight, "Betrag", "", 1, "R", false, 0, "")
 	pdf.HLine(0, false, ColorTeal)
 }
 
-func (pdf PDF) addTableRows(transactions Transactions, rowHeight float64, baseCurrency string) {
-	pdf.SetFont(pdf.FontFamily, "", 7)
+// Adds a row per transaction. Long descriptions are wrapped onto additional
+// lines, notes printed as footnotes are referenced by their number. If a row
+// doesn't fit above maxY, pageBreak is called to continue the table on a new
+// page, it returns the limit of the new page. Returns the limit of the last
+// page.
+func (pdf PDF) addTableRows(doc Document, notes []footnote, rowHeight float64, base Currency, maxY float64, pageBreak func() float64) float64 {
+	pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table)
+	lineHeight := rowHeight * .7
+	bookmarked := map[string]bool{}
 
 	// First row of the group
 	first := true
 	previousIdent := ""
-	for _, tx := range transactions {
+	for i, tx := range doc.Transactions {
+		// Set the font before measuring the description.
+		style := ""
+		if tx.IsAPARAuxiliary(pdf.CashBasisAccounting) {
+			style = "I"
+		}
+		pdf.SetFont(pdf.FontFamily, style, pdf.fonts.Sizes.Table)
+		description := tx.FmtDescription()
+		ref := footnoteRef(notes, i)
+		if ref != "" && !pdf.showsNotes() {
+			description = fmt.Sprint(description, " ", ref)
+		}
+		lines := []string{description}
+		if !pdf.truncateDescription {
+			lines = pdf.wrapText(description, pdf.columns.Description-1.5)
+		}
+		height := rowHeight + float64(len(lines)-1)*lineHeight
+		if !first && pdf.GetY()+height > maxY {
+			maxY = pageBreak()
+			pdf.SetFont(pdf.FontFamily, style, pdf.fonts.Sizes.Table)
+			first = true
+			previousIdent = ""
+		}
+		rowY := pdf.GetY()
+		if !bookmarked[tx.Ident] {
+			bookmarked[tx.Ident] = true
+			for _, entry := range doc.Outline {
+				if entry.Ident == tx.Ident {
+					pdf.Bookmark(entry.Title, outlineLevel(doc)+1, rowY)
+				}
+			}
+		}
 
 		if previousIdent != tx.Ident {
 			if !first {
 				pdf.HLine(0, false, ColorMagenta)
 			}
 
+			// Links to the first other receipt with the same doc number.
+			link := 0
+			if related := doc.RelatedByIdent(tx.Ident); len(related) != 0 {
+				link = pdf.docLink(related[0].Path)
+				pdf.SetTextColor(ColorTeal.GetValues())
+			}
+			identHeight := rowHeight
+			if tx.Ident == "" {
+				identHeight = height
+			}
 			pdf.SetCellMargin(1.5)
-			pdf.CellFormat(23, rowHeight, tx.Ident, "", 0, "L", tx.Ident == "", 0, "")
+			pdf.CellFormat(pdf.columns.Ident, identHeight, tx.Ident, "", 0, "L", tx.Ident == "", link, "")
 			pdf.SetCellMargin(0)
+			pdf.SetTextColor(0, 0, 0)
 		} else {
 			// Empty Ident cell for subsequent rows
-			pdf.HLine(23, true, ColorGreen)
-			pdf.CellFormat(23, rowHeight, "", "", 0, "L", false, 0, "")
+			pdf.HLine(pdf.columns.Ident, true, ColorGreen)
+			pdf.CellFormat(pdf.columns.Ident, rowHeight, "", "", 0, "L", false, 0, "")
 		}
 
 		// Set the font color to gray for AP/AR auxiliary transactions.
 		if tx.IsAPARAuxiliary(pdf.CashBasisAccounting) {
 			pdf.SetTextColor(120, 120, 120)
-			pdf.SetFont(pdf.FontFamily, "I", 7)
 		}
 
 		// Add transaction details
-		pdf.TableCell(14, rowHeight, tx.FmtDate(), "", 0, "L")
-		pdf.TableCell(103.49, rowHeight, tx.FmtDescription(), "", 0, "L")
+		pdf.TableCell(pdf.columns.Date, rowHeight, tx.FmtDate(), "", 0, "L")
+		descriptionX := pdf.GetX()
+		for i, line := range lines {
+			pdf.SetXY(descriptionX, rowY+float64(i)*lineHeight)
+			pdf.TableCell(pdf.columns.Description, rowHeight, line, "", 0, "L")
+		}
+		pdf.SetXY(descriptionX+pdf.columns.Description, rowY)
+		for _, field := range pdf.extraColumns {
+			value := field.Fmt(tx.Values[field.ID])
+			if field.ID == NOTES_FIELD && ref != "" {
+				value = ref
+			}
+			pdf.TableCell(pdf.columns.Extra, rowHeight, value, "", 0, field.align())
+		}
 		if !tx.IsAPARAuxiliary(pdf.CashBasisAccounting) {
 			// Default case: AP/AR auxiliary transaction in cash basis accounting.
-			pdf.TableCell(14, rowHeight, tx.GetAccountDebit(pdf.CashBasisAccounting), "", 0, "R")
-			pdf.TableCell(14, rowHeight, tx.GetAccountCredit(pdf.CashBasisAccounting), "", 0, "R")
+			debit, credit := pdf.fileType.Accounts(tx)
+			pdf.TableCell(pdf.columns.Debit, rowHeight, debit, "", 0, "R")
+			pdf.TableCell(pdf.columns.Credit, rowHeight, credit, "", 0, "R")
 		} else {
 			// pdf.SetFont(pdf.FontFamily, "I", 7)
-			pdf.TableCell(28, rowHeight, fmt.Sprintf("%s (KS 3)", tx.Cc3), "", 0, "R")
+			pdf.TableCell(pdf.columns.Debit+pdf.columns.Credit, rowHeight, fmt.Sprintf("%s (KS 3)", tx.Cc3), "", 0, "R")
 			// pdf.SetFont(pdf.FontFamily, "", 7)
 		}
+		if pdf.fileType.HasVAT() {
+			pdf.TableCell(pdf.columns.Vat, rowHeight, tx.VatCode, "", 0, "R")
+		}
 
-		if tx.ExchangeCurrency != "" && tx.ExchangeCurrency != baseCurrency {
-			pdf.ForeignAmountTableCell(20, rowHeight, tx, baseCurrency)
+		// Highlight amounts which couldn't be found on the receipt.
+		marker := ""
+		if doc.Verification.IsAmountMissing(tx) {
+			marker = VERIFICATION_MARKER
+		}
+		if tx.IsRateDeviating {
+			marker = strings.TrimSpace(fmt.Sprint(marker, " ", RATE_DEVIATION_MARKER))
+		}
+		if marker != "" {
+			pdf.SetTextColor(ColorVermilion.GetValues())
+		}
+		if tx.IsForeignCurrency(base) {
+			pdf.ForeignAmountTableCell(pdf.columns.Amount, rowHeight, tx, base, marker)
 		} else {
-			amount := fmt.Sprint(tx.GetAmount(pdf.CashBasisAccounting), " ", baseCurrency)
-			pdf.TableCell(20, rowHeight, amount, "", 1, "R")
+			amount := fmt.Sprint(tx.GetAmount(pdf.CashBasisAccounting, base), " ", base.Symbol)
+			if marker != "" {
+				amount = fmt.Sprint(marker, " ", amount)
+			}
+			pdf.TableCell(pdf.columns.Amount, rowHeight, amount, "", 1, "R")
+		}
+		if marker != "" {
+			pdf.SetTextColor(0, 0, 0)
 		}
 
+		pdf.SetY(rowY + height)
 		pdf.SetDashPattern([]float64{}, 0)
 		first = false
 		previousIdent = tx.Ident
@@ -260,10 +657,509 @@ func (pdf PDF) addTableRows(transactions Transactions, rowHeight float64, baseCu
 		// Reset the font color to black.
 		if tx.IsAPARAuxiliary(pdf.CashBasisAccounting) {
 			pdf.SetTextColor(0, 0, 0)
-			pdf.SetFont(pdf.FontFamily, "", 7)
+			pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table)
+		}
+	}
+	pdf.HLine(0, false, ColorMagenta)
+	return maxY
+}
+
+// Widths of the columns of the statement table, the counter account and
+// balance take the place of debit and credit.
+func (pdf PDF) statementColumns() (description, tick float64) {
+	tick = 6 * pdf.AreaWidth / 190
+	extra := float64(len(pdf.extraColumns)) * pdf.columns.Extra
+	return pdf.columns.Description + pdf.columns.Credit + pdf.columns.Vat + extra - pdf.columns.Amount - tick, tick
+}
+
+func (pdf PDF) addStatementHeader(rowHeight float64) {
+	descriptionWidth, tickWidth := pdf.statementColumns()
+	pdf.SetFont(pdf.FontFamily, "B", pdf.fonts.Sizes.Table)
+	pdf.SetCellMargin(1.5)
+	pdf.CellFormat(pdf.columns.Ident, rowHeight, "Beleg", "", 0, "L", false, 0, "")
+	pdf.SetCellMargin(0)
+	pdf.CellFormat(pdf.columns.Date, rowHeight, "Datum", "", 0, "L", false, 0, "")
+	pdf.CellFormat(descriptionWidth, rowHeight, "Beschreibung", "", 0, "L", false, 0, "")
+	pdf.CellFormat(pdf.columns.Debit, rowHeight, "Gegenkonto", "", 0, "R", false, 0, "")
+	pdf.CellFormat(pdf.columns.Amount, rowHeight, "Betrag", "", 0, "R", false, 0, "")
+	pdf.CellFormat(pdf.columns.Amount, rowHeight, "Saldo", "", 0, "R", false, 0, "")
+	pdf.CellFormat(tickWidth, rowHeight, "", "", 1, "R", false, 0, "")
+	pdf.HLine(0, false, ColorTeal)
+}
+
+// Lists all bookings against the account of the statement between the
+// opening and closing balance, each with a box to tick it off against the
+// statement. Bookings linked to other receipts link to them. Breaks the
+// page like addTableRows.
+func (pdf PDF) addStatementRows(doc Document, rowHeight float64, maxY float64, pageBreak func() float64) float64 {
+	statement := doc.Statement
+	descriptionWidth, tickWidth := pdf.statementColumns()
+	lineHeight := rowHeight * .7
+	amount := func(value float64) string {
+		return LookupCurrency(statement.Currency).Fmt(value)
+	}
+	balanceRow := func(label string, date time.Time, balance float64) {
+		pdf.SetFont(pdf.FontFamily, "B", pdf.fonts.Sizes.Table)
+		pdf.CellFormat(pdf.columns.Ident, rowHeight, "", "", 0, "L", false, 0, "")
+		pdf.TableCell(pdf.columns.Date, rowHeight, date.Format(DATE_FORMAT), "", 0, "L")
+		description := fmt.Sprintf("%s %s %s", label, statement.Account, statement.Description)
+		pdf.TableCell(descriptionWidth+pdf.columns.Debit+pdf.columns.Amount, rowHeight, description, "", 0, "L")
+		pdf.TableCell(pdf.columns.Amount, rowHeight, amount(balance), "", 0, "R")
+		pdf.CellFormat(tickWidth, rowHeight, "", "", 1, "R", false, 0, "")
+	}
+	bookmarked := map[string]bool{}
+
+	balanceRow("Anfangssaldo", statement.From, statement.Opening)
+	pdf.HLine(0, false, ColorMagenta)
+	pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table)
+	for _, entry := range statement.Entries {
+		tx := entry.Booking
+		lines := []string{tx.FmtDescription()}
+		if !pdf.truncateDescription {
+			lines = pdf.wrapText(tx.FmtDescription(), descriptionWidth-1.5)
+		}
+		height := rowHeight + float64(len(lines)-1)*lineHeight
+		if pdf.GetY()+height > maxY {
+			maxY = pageBreak()
+			pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table)
 		}
+		rowY := pdf.GetY()
+		if entry.IsLinked && !bookmarked[tx.Ident] {
+			bookmarked[tx.Ident] = true
+			for _, outline := range doc.Outline {
+				if outline.Ident == tx.Ident {
+					pdf.Bookmark(outline.Title, outlineLevel(doc)+1, rowY)
+				}
+			}
+		}
+
+		link := 0
+		if !entry.IsLinked && tx.Path != "" {
+			link = pdf.docLink(strings.TrimSpace(tx.Path))
+			pdf.SetTextColor(ColorTeal.GetValues())
+		}
+		pdf.SetCellMargin(1.5)
+		pdf.CellFormat(pdf.columns.Ident, rowHeight, tx.Ident, "", 0, "L", false, link, "")
+		pdf.SetCellMargin(0)
+		pdf.SetTextColor(0, 0, 0)
+
+		pdf.TableCell(pdf.columns.Date, rowHeight, tx.FmtDate(), "", 0, "L")
+		descriptionX := pdf.GetX()
+		for i, line := range lines {
+			pdf.SetXY(descriptionX, rowY+float64(i)*lineHeight)
+			pdf.TableCell(descriptionWidth, rowHeight, line, "", 0, "L")
+		}
+		pdf.SetXY(descriptionX+descriptionWidth, rowY)
+		counterAccount := tx.AccountDebit
+		if counterAccount == statement.Account {
+			counterAccount = tx.AccountCredit
+		}
+		if pdf.CashBasisAccounting {
+			counterAccount = tx.Category
+		}
+		pdf.TableCell(pdf.columns.Debit, rowHeight, counterAccount, "", 0, "R")
+		pdf.TableCell(pdf.columns.Amount, rowHeight, amount(entry.Amount), "", 0, "R")
+		pdf.TableCell(pdf.columns.Amount, rowHeight, amount(entry.Balance), "", 0, "R")
+		boxSize := rowHeight * .5
+		pdf.Rect(pdf.GetX()+(tickWidth-boxSize)/2, rowY+(rowHeight-boxSize)/2, boxSize, boxSize, "D")
+
+		pdf.SetY(rowY + height)
+		pdf.HLine(0, true, ColorGreen)
 	}
+	if pdf.GetY()+rowHeight > maxY {
+		maxY = pageBreak()
+	}
+	balanceRow("Schlusssaldo", statement.To, statement.Closing)
 	pdf.HLine(0, false, ColorMagenta)
+	pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table)
+	return maxY
+}
+
+// Lists the bookings marked with RATE_DEVIATION_MARKER with the booked rate
+// and the one of the exchange rates table.
+func (pdf PDF) addRateDeviationSummary(docs Documents) {
+	title := "Exchange rate deviations"
+	pdf.SetAutoPageBreak(true, pdf.BottomMargin)
+	defer pdf.SetAutoPageBreak(false, pdf.BottomMargin)
+	pdf.AddPage()
+	pdf.Bookmark(title, 0, -1)
+	pdf.TextCell(pdf.AreaWidth, 6.5, title, 1, "LT", 18, "B", 1.5, "", false)
+
+	intro := fmt.Sprintf(
+		"Amounts marked with \"%s\" in the transaction table were booked with an exchange rate deviating more than %g%% from the rate of the exchange rates table on the date of the booking. The rates below and in the table are the values of one unit of the currency.",
+		RATE_DEVIATION_MARKER, pdf.maxRateDeviation,
+	)
+	pdf.MultilineTextCell(pdf.AreaWidth, 1.3, intro, "L", 9, "", 1.5)
+	pdf.Ln(3)
+
+	for _, doc := range docs {
+		page, y := pdf.PageNo(), pdf.GetY()
+		pdf.MultilineTextCell(pdf.AreaWidth, 1.3, doc.Path, "L", 9, "B", 1.5)
+		if pdf.PageNo() == page {
+			pdf.Link(pdf.LeftMargin, y, pdf.AreaWidth, pdf.GetY()-y, pdf.docLink(doc.Path))
+		}
+		messages := []string{}
+		for _, tx := range doc.Transactions {
+			if !tx.IsRateDeviating {
+				continue
+			}
+			messages = append(messages, fmt.Sprintf(
+				"- Booking %s (%s %s): rate %s, exchange rates table %s, deviation %.2f%%",
+				tx.Unique, normalizeCurrencyCode(tx.ExchangeCurrency), tx.FmtDate(),
+				tx.FmtExchangeRate(pdf.exchangeRateDecimals), tx.ReferenceRate.Fmt(pdf.exchangeRateDecimals), tx.RateDeviation,
+			))
+		}
+		pdf.MultilineTextCell(pdf.AreaWidth, 1.3, strings.Join(messages, "\n"), "L", 8, "", 1.5)
+		pdf.Ln(1.5)
+	}
+}
+
+func (pdf PDF) addVerificationSummary(dossier Dossier) {
+	title := "Verification summary"
+	pdf.SetAutoPageBreak(true, pdf.BottomMargin)
+	defer pdf.SetAutoPageBreak(false, pdf.BottomMargin)
+	pdf.AddPage()
+	pdf.Bookmark(title, 0, -1)
+	pdf.TextCell(pdf.AreaWidth, 6.5, title, 1, "LT", 18, "B", 1.5, "", false)
+
+	issues := dossier.VerificationIssues()
+	intro := fmt.Sprintf(
+		"The text of each receipt was searched for the booked amounts. Amounts marked with \"%s\" in the transaction table couldn't be found.",
+		VERIFICATION_MARKER,
+	)
+	if len(issues) == 0 {
+		intro = "The booked amounts of all receipts were found in the text of the receipts."
+	}
+	pdf.MultilineTextCell(pdf.AreaWidth, 1.3, intro, "L", 9, "", 1.5)
+	pdf.Ln(3)
+
+	for _, doc := range issues {
+		page, y := pdf.PageNo(), pdf.GetY()
+		pdf.MultilineTextCell(pdf.AreaWidth, 1.3, doc.Path, "L", 9, "B", 1.5)
+		if pdf.PageNo() == page {
+			pdf.Link(pdf.LeftMargin, y, pdf.AreaWidth, pdf.GetY()-y, pdf.docLink(doc.Path))
+		}
+		messages := []string{}
+		for _, msg := range doc.Verification.Messages(doc) {
+			messages = append(messages, fmt.Sprint("- ", msg))
+		}
+		pdf.MultilineTextCell(pdf.AreaWidth, 1.3, strings.Join(messages, "\n"), "L", 8, "", 1.5)
+		pdf.Ln(1.5)
+	}
+}
+
+// Income and expenses of all bookings by group and category, following the
+// sections of the Anlage EÜR.
+func (pdf PDF) addCashBasisSummary(dossier Dossier) {
+	title := "Einnahmenüberschussrechnung"
+	pdf.SetAutoPageBreak(true, pdf.BottomMargin)
+	defer pdf.SetAutoPageBreak(false, pdf.BottomMargin)
+	pdf.AddPage()
+	pdf.Bookmark(title, 0, -1)
+	pdf.TextCell(pdf.AreaWidth, 6.5, title, 1, "LT", 18, "B", 1.5, "", false)
+	pdf.MultilineTextCell(pdf.AreaWidth, 1.3, fmt.Sprint("Zeitraum: ", dossier.FmtPeriod()), "L", 9, "", 1.5)
+	pdf.Ln(3)
+
+	summary, err := dossier.CashBasisSummary()
+	if err != nil {
+		fmt.Println(err)
+		pdf.MultilineTextCell(pdf.AreaWidth, 1.3, fmt.Sprint("The summary couldn't be computed: ", err), "L", 9, "", 1.5)
+		return
+	}
+	rowHeight := 5.
+	countWidth := 25.
+	amountWidth := 30.
+	labelWidth := pdf.AreaWidth - countWidth - amountWidth
+	amount := dossier.Currency().Fmt
+	row := func(label, count string, value float64, style string, indent float64) {
+		pdf.SetFont(pdf.FontFamily, style, 9)
+		pdf.SetCellMargin(1.5 + indent)
+		pdf.TableCell(labelWidth, rowHeight, label, "", 0, "L")
+		pdf.SetCellMargin(1.5)
+		pdf.TableCell(countWidth, rowHeight, count, "", 0, "R")
+		pdf.TableCell(amountWidth, rowHeight, amount(value), "", 1, "R")
+	}
+
+	pdf.SetFont(pdf.FontFamily, "B", 9)
+	pdf.SetCellMargin(1.5)
+	pdf.CellFormat(labelWidth, rowHeight, "", "", 0, "L", false, 0, "")
+	pdf.CellFormat(countWidth, rowHeight, "Buchungen", "", 0, "R", false, 0, "")
+	pdf.CellFormat(amountWidth, rowHeight, "Betrag", "", 1, "R", false, 0, "")
+	pdf.HLine(0, false, ColorTeal)
+	for _, section := range []struct {
+		title   string
+		section CashBasisSection
+	}{{"Betriebseinnahmen", summary.Income}, {"Betriebsausgaben", summary.Expenses}} {
+		pdf.SetFont(pdf.FontFamily, "B", 10)
+		pdf.SetCellMargin(1.5)
+		pdf.CellFormat(pdf.AreaWidth, rowHeight+1, section.title, "", 1, "L", false, 0, "")
+		for _, group := range section.section.Groups {
+			label := group.Description
+			switch {
+			case label != "":
+			case group.Code != "":
+				label = group.Code
+			default:
+				label = "Ohne Gruppe"
+			}
+			for _, category := range group.Categories {
+				if category.IsUnknown {
+					pdf.SetTextColor(ColorVermilion.GetValues())
+				}
+				text := category.String()
+				if category.Code == "" {
+					text = "Ohne Kategorie"
+				}
+				row(text, strconv.Itoa(category.Count), category.Total, "", 4)
+				pdf.SetTextColor(0, 0, 0)
+			}
+			row(fmt.Sprint("Summe ", label), "", group.Total, "I", 4)
+			pdf.Ln(1)
+		}
+		pdf.HLine(0, false, ColorMagenta)
+		row(fmt.Sprint("Summe ", section.title), "", section.section.Total, "B", 0)
+		pdf.Ln(3)
+	}
+	pdf.HLine(0, false, ColorMagenta)
+	label := "Gewinn"
+	if summary.Profit() < 0 {
+		label = "Verlust"
+	}
+	row(label, "", summary.Profit(), "B", 0)
+
+	// Subtotals are only of interest if there are foreign currencies.
+	totals, err := dossier.bookings.CurrencyTotals(dossier.Currency(), true)
+	if err != nil || len(totals) < 2 {
+		pdf.SetCellMargin(0)
+		return
+	}
+	pdf.Ln(3)
+	pdf.SetFont(pdf.FontFamily, "B", 10)
+	pdf.SetCellMargin(1.5)
+	pdf.CellFormat(pdf.AreaWidth, rowHeight+1, "Nach Währung", "", 1, "L", false, 0, "")
+	for _, total := range totals {
+		text := total.Currency.Code
+		if total.Currency.Code != dossier.BaseCurrency {
+			text = fmt.Sprintf("%s (%s)", text, total.Currency.Fmt(total.Amount))
+		}
+		row(text, "", total.Base, "", 4)
+	}
+	pdf.SetCellMargin(0)
+}
+
+// Subtotals of the bookings by currency, foreign currencies with the amount
+// in the currency and in base currency.
+func (pdf PDF) addCurrencyTotals(doc Document, base Currency, rowHeight float64) {
+	totals, err := doc.Transactions.CurrencyTotals(base, pdf.CashBasisAccounting)
+	if err != nil {
+		fmt.Printf("%s: %s\n", doc.Path, err)
+		return
+	}
+	entries := []string{}
+	for _, total := range totals {
+		if total.Currency.Code == base.Code {
+			if total.Base != 0 {
+				entries = append(entries, base.Fmt(total.Base))
+			}
+			continue
+		}
+		entries = append(entries, fmt.Sprintf("%s (%s)", total.Currency.Fmt(total.Amount), base.Fmt(total.Base)))
+	}
+	pdf.SetFont(pdf.FontFamily, "B", pdf.fonts.Sizes.Table)
+	pdf.SetCellMargin(1.5)
+	pdf.CellFormat(pdf.columns.Ident, rowHeight, "Summen", "", 0, "L", false, 0, "")
+	pdf.SetCellMargin(0)
+	pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table)
+	pdf.TableCell(pdf.AreaWidth-pdf.columns.Ident, rowHeight, strings.Join(entries, " · "), "", 1, "L")
+}
+
+// Entry of a legend below the transaction table.
+type legendEntry struct {
+	Text      string
+	IsUnknown bool
+}
+
+func accountLegend(doc Document) []legendEntry {
+	rsl := []legendEntry{}
+	for _, account := range doc.Accounts {
+		rsl = append(rsl, legendEntry{account.String(), account.IsUnknown})
+	}
+	return rsl
+}
+
+func categoryLegend(doc Document) []legendEntry {
+	rsl := []legendEntry{}
+	for _, category := range doc.Categories {
+		rsl = append(rsl, legendEntry{category.String(), category.IsUnknown})
+	}
+	return rsl
+}
+
+// Names of the accounts or categories used in the transaction table.
+// Entries missing in the accounts or categories table are highlighted.
+func (pdf PDF) addLegend(label string, entries []legendEntry, rowHeight float64) {
+	pdf.SetFont(pdf.FontFamily, "B", pdf.fonts.Sizes.Table)
+	pdf.SetCellMargin(1.5)
+	pdf.CellFormat(pdf.columns.Ident, rowHeight, label, "", 0, "L", false, 0, "")
+	pdf.SetCellMargin(0)
+	pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table)
+	maxX := pdf.PageWidth - pdf.RightMargin
+	for i, entry := range entries {
+		text := entry.Text
+		if entry.IsUnknown {
+			text += " (unbekannt)"
+			pdf.SetTextColor(ColorVermilion.GetValues())
+		}
+		if i != len(entries)-1 {
+			text += ", "
+		}
+		width := pdf.GetStringWidth(text)
+		if pdf.GetX()+width > maxX {
+			pdf.SetTextColor(0, 0, 0)
+			pdf.CellFormat(pdf.GetStringWidth("…"), rowHeight, "…", "", 0, "L", false, 0, "")
+			break
+		}
+		pdf.CellFormat(width, rowHeight, text, "", 0, "L", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	}
+	pdf.Ln(rowHeight)
+}
+
+// Note of a booking printed below the transaction table.
+type footnote struct {
+	// Index of the booking within the transactions of the document, the
+	// unique id of a booking isn't unique in every export.
+	Transaction int
+	Text        string
+}
+
+// Whether the notes column is one of the extra columns.
+func (pdf PDF) showsNotes() bool {
+	return slices.ContainsFunc(pdf.extraColumns, func(f Field) bool {
+		return f.ID == NOTES_FIELD
+	})
+}
+
+// Notes of the bookings printed as footnotes: the ones too long for the
+// notes column or, if the column isn't shown, all of them.
+func (pdf PDF) footnotes(doc Document) []footnote {
+	if !pdf.notesFootnotes {
+		return nil
+	}
+	pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table)
+	rsl := []footnote{}
+	for i, tx := range doc.Transactions {
+		note := removeExtraSpaces(tx.Values[NOTES_FIELD])
+		if note == "" || (pdf.showsNotes() && pdf.GetStringWidth(note) <= pdf.columns.Extra-1.5) {
+			continue
+		}
+		rsl = append(rsl, footnote{i, note})
+	}
+	return rsl
+}
+
+// Reference of the footnote of the transaction with the given index, empty
+// if its note isn't printed as footnote.
+func footnoteRef(notes []footnote, transaction int) string {
+	for i, note := range notes {
+		if note.Transaction == transaction {
+			return fmt.Sprintf("[%d]", i+1)
+		}
+	}
+	return ""
+}
+
+// Lines of the footnote with the given number.
+func (pdf PDF) footnoteLines(i int, note footnote) []string {
+	pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table)
+	return pdf.wrapText(fmt.Sprintf("[%d] %s", i+1, note.Text), pdf.AreaWidth-pdf.columns.Ident-1.5)
+}
+
+func (pdf PDF) footnotesHeight(notes []footnote, rowHeight float64) float64 {
+	rsl := 0.
+	for i, note := range notes {
+		rsl += rowHeight + float64(len(pdf.footnoteLines(i, note))-1)*rowHeight*.7
+	}
+	return rsl
+}
+
+// Notes of the bookings which don't fit into the transaction table, long
+// notes are wrapped like the descriptions.
+func (pdf PDF) addFootnotes(notes []footnote, rowHeight float64) {
+	lineHeight := rowHeight * .7
+	pdf.SetFont(pdf.FontFamily, "B", pdf.fonts.Sizes.Table)
+	pdf.SetCellMargin(1.5)
+	pdf.CellFormat(pdf.columns.Ident, rowHeight, "Notizen", "", 0, "L", false, 0, "")
+	pdf.SetCellMargin(0)
+	pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table)
+	x := pdf.GetX()
+	for i, note := range notes {
+		lines := pdf.footnoteLines(i, note)
+		y := pdf.GetY()
+		for j, line := range lines {
+			pdf.SetXY(x, y+float64(j)*lineHeight)
+			pdf.TableCell(pdf.AreaWidth-pdf.columns.Ident, rowHeight, line, "", 0, "L")
+		}
+		pdf.SetY(y + rowHeight + float64(len(lines)-1)*lineHeight)
+	}
+}
+
+// Links to the other receipts with the same doc number below the
+// transaction table.
+func (pdf PDF) addRelatedDocuments(doc Document, rowHeight float64) {
+	pdf.SetFont(pdf.FontFamily, "B", pdf.fonts.Sizes.Table)
+	pdf.SetCellMargin(1.5)
+	pdf.CellFormat(pdf.columns.Ident, rowHeight, "Siehe auch", "", 0, "L", false, 0, "")
+	pdf.SetCellMargin(0)
+	pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table)
+	pdf.SetTextColor(ColorTeal.GetValues())
+	maxX := pdf.PageWidth - pdf.RightMargin
+	for i, related := range doc.Related {
+		text := fmt.Sprintf("%s (%s)", related.Title, related.Ident)
+		if i != len(doc.Related)-1 {
+			text += ", "
+		}
+		width := pdf.GetStringWidth(text)
+		if pdf.GetX()+width > maxX {
+			pdf.CellFormat(pdf.GetStringWidth("…"), rowHeight, "…", "", 0, "L", false, 0, "")
+			break
+		}
+		pdf.CellFormat(width, rowHeight, text, "", 0, "L", false, pdf.docLink(related.Path), "")
+	}
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(rowHeight)
+}
+
+// Shows the payment information of the QR-bill found on the receipt
+// below the transaction table. Mismatches with the bookings are highlighted.
+func (pdf PDF) addQRBillInfo(doc Document, rowHeight float64) {
+	pdf.SetFont(pdf.FontFamily, "B", pdf.fonts.Sizes.Table)
+	pdf.SetCellMargin(1.5)
+	pdf.CellFormat(pdf.columns.Ident, rowHeight, "QR-Rechnung", "", 0, "L", false, 0, "")
+	pdf.SetCellMargin(0)
+	pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table)
+	pdf.TableCell(pdf.AreaWidth-pdf.columns.Ident, rowHeight, doc.QRBill.String(), "", 1, "L")
+
+	if len(doc.QRBillMismatches) == 0 {
+		return
+	}
+	pdf.SetTextColor(ColorVermilion.GetValues())
+	for _, mismatch := range doc.QRBillMismatches {
+		pdf.CellFormat(pdf.columns.Ident, rowHeight, "", "", 0, "L", false, 0, "")
+		pdf.TableCell(pdf.AreaWidth-pdf.columns.Ident, rowHeight, mismatch, "", 1, "L")
+	}
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// Shows the key figures of the e-invoice embedded into the receipt below
+// the transaction table.
+func (pdf PDF) addEInvoiceInfo(doc Document, rowHeight float64) {
+	pdf.SetFont(pdf.FontFamily, "B", pdf.fonts.Sizes.Table)
+	pdf.SetCellMargin(1.5)
+	pdf.CellFormat(pdf.columns.Ident, rowHeight, "E-Rechnung", "", 0, "L", false, 0, "")
+	pdf.SetCellMargin(0)
+	pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table)
+	pdf.TableCell(pdf.AreaWidth-pdf.columns.Ident, rowHeight, doc.EInvoice.String(), "", 1, "L")
 }
 
 type EmbedError struct {
@@ -290,8 +1186,20 @@ func (pdf PDF) embedDocument(dossier Dossier, doc Document, page int, footerHeig
 			Error:     err,
 		})
 	}
+	if doc.IsXMLInvoice {
+		if doc.EInvoice != nil {
+			pdf.addEInvoiceContent(*doc.EInvoice, footerHeight)
+			return 1
+		}
+		errors = append(errors, EmbedError{
+			Operation: "reading e-invoice",
+			Error:     doc.FileError,
+		})
+		pdf.addHandleEmbedPDFErrors(errors, path)
+		return 1
+	}
 	var embedErr error
-	pageCount = pdf.embedPDF(path, 1, pdf.GetY(), footerHeight, &embedErr)
+	pageCount = pdf.embedPDF(path, page, doc.Page(page), pdf.GetY(), footerHeight, &embedErr)
 	if embedErr != nil {
 		errors = append(errors, EmbedError{
 			Operation: "embedding file",
@@ -304,25 +1212,113 @@ func (pdf PDF) embedDocument(dossier Dossier, doc Document, page int, footerHeig
 	return pageCount
 }
 
-func (pdf PDF) embedPDF(path string, page int, tableBottomY, footerHeight float64, err *error) (totalPages int) {
+// Landscape receipts are turned by 90 degrees if this enlarges them at
+// least by this factor.
+const ROTATE_MIN_GAIN = 1.2
+
+// Embeds the given page of the receipt below the table. The page is shown as
+// in a PDF viewer (crop box and /Rotate), limited to its content area and
+// turned counter-clockwise if this makes better use of the space.
+func (pdf PDF) embedPDF(path string, page int, geometry ReceiptPage, tableBottomY, footerHeight float64, err *error) (totalPages int) {
 	defer func() {
 		if r := recover(); r != nil {
 			*err = fmt.Errorf("'%s', try to reexport the file in order to fix it", r)
 		}
 	}()
-	tpl := gofpdi.ImportPage(pdf, path, 1, "/MediaBox")
-	width, height := fitImage(
-		210,
-		297,
-		pdf.AreaWidth-2,
-		pdf.AreaHeight-tableBottomY-2,
-	)
-	x := (pdf.AreaWidth - 2 - width) / 2
-	gofpdi.UseImportedTemplate(pdf, tpl, pdf.LeftMargin+x+1, tableBottomY+1, width, height)
+	// gofpdi doesn't fall back to the media box for pages without crop box.
+	box := "/MediaBox"
+	if geometry.HasCropBox {
+		box = "/CropBox"
+	}
+	tpl := gofpdi.ImportPage(pdf, path, page, box)
+	content := geometry.Content
+	contentWidth := geometry.Width * content.Width()
+	contentHeight := geometry.Height * content.Height()
+	top := tableBottomY + 1
+	maxWidth := pdf.AreaWidth - 2
+	maxHeight := pdf.TopMargin + pdf.AreaHeight - footerHeight - top - 1
+
+	width, height := fitImage(contentWidth, contentHeight, maxWidth, maxHeight)
+	scale := width / contentWidth
+	rotatedWidth, rotatedHeight := fitImage(contentHeight, contentWidth, maxWidth, maxHeight)
+	rotate := rotatedHeight/contentWidth >= scale*ROTATE_MIN_GAIN
+	if rotate {
+		width, height = rotatedWidth, rotatedHeight
+		scale = rotatedHeight / contentWidth
+	}
+	x := pdf.LeftMargin + 1 + (maxWidth-width)/2
+	centerX, centerY := x+width/2, top+height/2
+
+	pdf.ClipRect(x, top, width, height, false)
+	if rotate {
+		pdf.TransformBegin()
+		pdf.TransformRotate(90, centerX, centerY)
+	}
+	// Position of the whole page such that the content is centered.
+	pageWidth, pageHeight := geometry.Width*scale, geometry.Height*scale
+	pageX := centerX - contentWidth*scale/2 - content.Left*pageWidth
+	pageY := centerY - contentHeight*scale/2 - content.Top*pageHeight
+	gofpdi.UseImportedTemplate(pdf, tpl, pageX, pageY, pageWidth, pageHeight)
+	if rotate {
+		pdf.TransformEnd()
+	}
+	pdf.ClipEnd()
 
 	return len(gofpdi.GetPageSizes())
 }
 
+// Renders a bare XML e-invoice as a readable page in place of the receipt.
+func (pdf PDF) addEInvoiceContent(invoice EInvoice, footerHeight float64) {
+	rowHeight := 5.
+	maxY := pdf.TopMargin + pdf.AreaHeight - footerHeight - rowHeight
+	labelWidth := 35.
+	amountWidth := 30.
+
+	pdf.SetY(pdf.GetY() + 4)
+	pdf.TextCell(pdf.AreaWidth, 6.5, fmt.Sprintf("E-Rechnung (%s)", invoice.Syntax), 1, "LT", 14, "B", 1.5, "", false)
+	pdf.Ln(2)
+
+	fields := [][2]string{
+		{"Rechnungsnummer", invoice.Number},
+		{"Rechnungsdatum", invoice.FmtIssueDate()},
+		{"Verkäufer", invoice.Seller},
+		{"Käufer", invoice.Buyer},
+		{"Nettobetrag", fmt.Sprint(invoice.NetTotal, " ", invoice.Currency)},
+		{"MwSt.", fmt.Sprint(invoice.TaxTotal, " ", invoice.Currency)},
+		{"Bruttobetrag", fmt.Sprint(invoice.GrossTotal, " ", invoice.Currency)},
+		{"Zahlbetrag", fmt.Sprint(invoice.DueAmount, " ", invoice.Currency)},
+	}
+	for _, field := range fields {
+		pdf.SetFont(pdf.FontFamily, "B", 9)
+		pdf.SetCellMargin(1.5)
+		pdf.CellFormat(labelWidth, rowHeight, field[0], "", 0, "L", false, 0, "")
+		pdf.SetFont(pdf.FontFamily, "", 9)
+		pdf.TableCell(pdf.AreaWidth-labelWidth, rowHeight, field[1], "", 1, "L")
+	}
+	if len(invoice.Lines) == 0 {
+		return
+	}
+
+	pdf.Ln(4)
+	pdf.SetFont(pdf.FontFamily, "B", 9)
+	pdf.CellFormat(pdf.AreaWidth-2*amountWidth, rowHeight, "Position", "", 0, "L", false, 0, "")
+	pdf.CellFormat(amountWidth, rowHeight, "Menge", "", 0, "R", false, 0, "")
+	pdf.CellFormat(amountWidth, rowHeight, "Nettobetrag", "", 1, "R", false, 0, "")
+	pdf.HLine(0, false, ColorTeal)
+	pdf.SetFont(pdf.FontFamily, "", 9)
+	for i, line := range invoice.Lines {
+		if pdf.GetY()+rowHeight > maxY {
+			remaining := fmt.Sprintf("… %d weitere Positionen", len(invoice.Lines)-i)
+			pdf.TableCell(pdf.AreaWidth, rowHeight, remaining, "", 1, "L")
+			break
+		}
+		pdf.TableCell(pdf.AreaWidth-2*amountWidth, rowHeight, line.Name, "", 0, "L")
+		pdf.TableCell(amountWidth, rowHeight, strings.TrimSpace(line.Quantity+" "+line.Unit), "", 0, "R")
+		pdf.TableCell(amountWidth, rowHeight, fmt.Sprint(line.NetAmount, " ", invoice.Currency), "", 1, "R")
+	}
+	pdf.SetCellMargin(0)
+}
+
 func (pdf PDF) addHandleEmbedPDFErrors(errors []EmbedError, path string) {
 	for _, err := range errors {
 		fmt.Printf("%s: error during %s\n", path, err.Operation)
@@ -334,7 +1330,7 @@ func (pdf PDF) addHandleEmbedPDFErrors(errors []EmbedError, path string) {
 	}
 	pdf.ImageOptions(
 		"sad-document",
-		(pdf.AreaWidth-20)/2,
+		pdf.LeftMargin+(pdf.AreaWidth-40)/2,
 		pdf.GetY()+5,
 		40, 40, false, pdf.sadDocumentOptions, 0, "",
 	)
@@ -375,6 +1371,10 @@ func (pdf PDF) addFooter(dossier Dossier, doc Document, footerHeight float64, em
 		embedTotalPages = 1
 	}
 	countInfo := fmt.Sprintf("%d/%d – Page %d", embedPageNr, embedTotalPages, reportPageCount)
+	if embedPageNr == 0 {
+		// Page with transactions only.
+		countInfo = fmt.Sprintf("Transactions – Page %d", reportPageCount)
+	}
 	file := fmt.Sprint("File: ", filepath.Base(dossier.AccountingFilePath))
 	lastSaved := fmt.Sprint("Accounting data as of: ", dossier.FmtLastSaved())
 	createdAt := fmt.Sprint("Report was created on: ", time.Now().Format(DATE_TIME_FORMAT))
@@ -382,7 +1382,7 @@ func (pdf PDF) addFooter(dossier Dossier, doc Document, footerHeight float64, em
 	pdf.SetY(startY + .5)
 	lineHeight := (footerHeight - 1) / 3
 	cellWidth := pdf.AreaWidth / 3
-	size := 6.8
+	size := pdf.fonts.Sizes.Footer
 	pdf.TextCell(cellWidth, lineHeight, dossier.CompanyName, 0, "LM", size, "", .5, "", false)
 	pdf.TextCell(cellWidth, lineHeight, countInfo, 0, "CM", size, "B", .5, "", false)
 	pdf.TextCell(cellWidth, lineHeight, file, 1, "RM", size, "", .5, "", false)
@@ -462,9 +1462,11 @@ func (pdf PDF) TableCell(w, h float64, txtStr string, borderStr string, ln int,
 	}
 
 	for pdf.GetStringWidth(txtStr) > w-1.5 {
-		txtStr = strings.TrimSuffix(txtStr, "…")
-		txtStr = txtStr[:len(txtStr)-1]
-		txtStr = txtStr + "…"
+		runes := []rune(strings.TrimSuffix(txtStr, "…"))
+		if len(runes) == 0 {
+			break
+		}
+		txtStr = string(runes[:len(runes)-1]) + "…"
 	}
 
 	pdf.CellFormat(w, h, txtStr, borderStr, ln, alignStr, false, 0, "")
@@ -474,10 +1476,43 @@ func (pdf PDF) TableCell(w, h float64, txtStr string, borderStr string, ln int,
 	}
 }
 
+// Splits the text into lines fitting into the given width, at spaces where
+// possible and within words which are too long for one line.
+func (pdf PDF) wrapText(txt string, width float64) []string {
+	rsl := []string{}
+	line := ""
+	for _, word := range strings.Fields(txt) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if pdf.GetStringWidth(candidate) <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			rsl = append(rsl, line)
+		}
+		line = ""
+		for _, r := range word {
+			if line != "" && pdf.GetStringWidth(line+string(r)) > width {
+				rsl = append(rsl, line)
+				line = ""
+			}
+			line += string(r)
+		}
+	}
+	if line != "" || len(rsl) == 0 {
+		rsl = append(rsl, line)
+	}
+	return rsl
+}
+
 func (pdf PDF) ForeignAmountTableCell(
 	w, h float64,
 	transaction Transaction,
-	baseCurrency string,
+	base Currency,
+	marker string,
 ) {
 	drawR, drawG, drawB := pdf.setDebugDrawColor(pdf.debugCells, ColorVermilion)
 	borderStr := ""
@@ -486,24 +1521,36 @@ func (pdf PDF) ForeignAmountTableCell(
 	}
 	fontSize, _ := pdf.GetFontSize()
 
-	pdf.SetFont(pdf.FontFamily, "", 7)
-	_, height7pt := pdf.GetFontSize()
-	pdf.SetFont(pdf.FontFamily, "", 4)
-	_, height5pt := pdf.GetFontSize()
-	margin := (h - height7pt - height5pt) / 2
+	pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table)
+	_, amountHeight := pdf.GetFontSize()
+	pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table*4/7)
+	_, exchangeHeight := pdf.GetFontSize()
+	margin := (h - amountHeight - exchangeHeight) / 2
 
-	pdf.SetFont(pdf.FontFamily, "", 7)
-	baseAmount := fmt.Sprint(transaction.GetAmount(pdf.CashBasisAccounting), " ", baseCurrency)
-	pdf.CellFormat(w, height7pt+margin, baseAmount, borderStr, 2, "RB", false, 0, "")
+	pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table)
+	baseAmount := fmt.Sprint(transaction.GetAmount(pdf.CashBasisAccounting, base), " ", base.Symbol)
+	if marker != "" {
+		baseAmount = fmt.Sprint(marker, " ", baseAmount)
+	}
+	pdf.CellFormat(w, amountHeight+margin, baseAmount, borderStr, 2, "RB", false, 0, "")
 
+	currency := LookupCurrency(transaction.ExchangeCurrency)
+	currencyAmount := transaction.AmountCurrency
+	if value, err := transaction.CurrencyAmount(); err == nil {
+		currencyAmount = currency.FmtValue(value)
+	}
 	exchangeInfo := fmt.Sprintf(
 		"%s %s – %s",
-		transaction.AmountCurrency,
-		transaction.ExchangeCurrency,
-		transaction.ExchangeRate[:6],
+		currencyAmount,
+		currency.Code,
+		transaction.FmtExchangeRate(pdf.exchangeRateDecimals),
 	)
-	pdf.SetFont(pdf.FontFamily, "", 4)
-	pdf.CellFormat(w, height5pt+margin, exchangeInfo, borderStr, 2, "RT", false, 0, "")
+	// Rate of the exchange rates table on the date of the booking.
+	if transaction.ReferenceRate != nil {
+		exchangeInfo = fmt.Sprintf("%s (%s)", exchangeInfo, transaction.ReferenceRate.Fmt(pdf.exchangeRateDecimals))
+	}
+	pdf.SetFont(pdf.FontFamily, "", pdf.fonts.Sizes.Table*4/7)
+	pdf.CellFormat(w, exchangeHeight+margin, exchangeInfo, borderStr, 2, "RT", false, 0, "")
 
 	if pdf.debugCells {
 		pdf.SetDrawColor(drawR, drawG, drawB)
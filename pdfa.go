@@ -0,0 +1,200 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"slices"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// sRGB IEC61966-2.1 profile used as the output intent of PDF/A documents.
+//
+//go:embed static/srgb.icc
+var srgbProfile []byte
+
+const PDFA_OUTPUT_CONDITION = "sRGB IEC61966-2.1"
+
+// Annotation flags (PDF 32000-1, 12.5.3).
+const (
+	annotFlagInvisible    = 1
+	annotFlagHidden       = 2
+	annotFlagPrint        = 4
+	annotFlagNoView       = 32
+	annotFlagToggleNoView = 256
+)
+
+// ConvertToPDFA adds what PDF/A-3b requires on top of what fpdf writes: an
+// sRGB output intent and printable annotations. Fonts are embedded by fpdf
+// already. The PDF/A identification is part of the XMP metadata, see
+// AddXMPMetadata.
+func ConvertToPDFA() PostProcessStep {
+	return func(ctx *model.Context) error {
+		if err := addOutputIntent(ctx); err != nil {
+			return fmt.Errorf("failed to add PDF/A output intent: %w", err)
+		}
+		if err := fixAnnotationFlags(ctx); err != nil {
+			return fmt.Errorf("failed to update annotations for PDF/A: %w", err)
+		}
+		return nil
+	}
+}
+
+func addOutputIntent(ctx *model.Context) error {
+	profile, err := ctx.NewStreamDictForBuf(srgbProfile)
+	if err != nil {
+		return err
+	}
+	profile.InsertInt("N", 3)
+	if err := profile.Encode(); err != nil {
+		return err
+	}
+	profileRef, err := ctx.IndRefForNewObject(*profile)
+	if err != nil {
+		return err
+	}
+	intent := types.Dict{
+		"Type":                      types.Name("OutputIntent"),
+		"S":                         types.Name("GTS_PDFA1"),
+		"OutputConditionIdentifier": types.StringLiteral(PDFA_OUTPUT_CONDITION),
+		"Info":                      types.StringLiteral(PDFA_OUTPUT_CONDITION),
+		"DestOutputProfile":         *profileRef,
+	}
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return err
+	}
+	rootDict.Update("OutputIntents", types.Array{intent})
+	return nil
+}
+
+// PDF/A wants every annotation to be printed and never hidden.
+func fixAnnotationFlags(ctx *model.Context) error {
+	for page := 1; page <= ctx.PageCount; page++ {
+		pageDict, _, _, err := ctx.PageDict(page, false)
+		if err != nil {
+			return err
+		}
+		annots, err := ctx.DereferenceArray(pageDict["Annots"])
+		if err != nil {
+			return err
+		}
+		for _, obj := range annots {
+			annot, err := ctx.DereferenceDict(obj)
+			if err != nil || annot == nil {
+				return err
+			}
+			if subtype := annot.NameEntry("Subtype"); subtype != nil && *subtype == "Popup" {
+				continue
+			}
+			flags := 0
+			if value := annot.IntEntry("F"); value != nil {
+				flags = *value
+			}
+			flags &^= annotFlagInvisible | annotFlagHidden | annotFlagNoView | annotFlagToggleNoView
+			annot.Update("F", types.Integer(flags|annotFlagPrint))
+		}
+	}
+	return nil
+}
+
+// CheckPDFA searches the receipts for content which can't be carried over
+// into a PDF/A-3 report. Results are stored in Document.PDFAIssues. This is
+// a heuristic and doesn't replace a validator like veraPDF.
+func (d *Dossier) CheckPDFA() {
+	for i := range d.JournalEntries {
+		doc := &d.JournalEntries[i]
+		if !doc.IsValidFile || doc.IsXMLInvoice {
+			continue
+		}
+		issues, err := PDFAIssues(doc.AbsolutePath)
+		if err != nil {
+			issues = []string{fmt.Sprintf("couldn't be checked: %s", err)}
+		}
+		doc.PDFAIssues = issues
+		for _, issue := range issues {
+			fmt.Printf("%s: prevents PDF/A conformance: %s\n", doc.Path, issue)
+		}
+	}
+}
+
+// PDFAIssues lists the reasons why the PDF at the given path can't be
+// embedded into a PDF/A document.
+func PDFAIssues(path string) ([]string, error) {
+	ctx, err := api.ReadContextFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	rsl := []string{}
+	add := func(issue string) {
+		if !slices.Contains(rsl, issue) {
+			rsl = append(rsl, issue)
+		}
+	}
+	if ctx.Encrypt != nil {
+		add("file is encrypted")
+	}
+	for _, entry := range ctx.Table {
+		if entry == nil || entry.Free || entry.Object == nil {
+			continue
+		}
+		var dict types.Dict
+		switch obj := entry.Object.(type) {
+		case types.Dict:
+			dict = obj
+		case types.StreamDict:
+			dict = obj.Dict
+			for _, filter := range obj.FilterPipeline {
+				if filter.Name == "LZWDecode" {
+					add("uses LZW compression")
+				}
+			}
+		default:
+			continue
+		}
+
+		if action := dict.NameEntry("S"); (action != nil && *action == "JavaScript") || dict["JS"] != nil {
+			add("contains JavaScript")
+		}
+		if dict["TR"] != nil || dict["TR2"] != nil {
+			add("uses transfer functions")
+		}
+		if subtype := dict.NameEntry("Subtype"); subtype != nil && *subtype == "Image" {
+			if colorSpace := dict.NameEntry("ColorSpace"); colorSpace != nil && *colorSpace == "DeviceCMYK" {
+				add("contains CMYK images without an ICC profile")
+			}
+		}
+		if fontType := dict.NameEntry("Type"); fontType != nil && *fontType == "Font" {
+			if name, embedded := isFontEmbedded(ctx, dict); !embedded {
+				add(fmt.Sprintf("font %s is not embedded", name))
+			}
+		}
+	}
+	return rsl, nil
+}
+
+// Reports whether the program of a simple or CID font is embedded. Composite
+// (Type0) and Type3 fonts are always considered embedded, the descendant
+// fonts of the former are checked on their own.
+func isFontEmbedded(ctx *model.Context, font types.Dict) (string, bool) {
+	name := UNKNOWN_STR
+	if baseFont := font.NameEntry("BaseFont"); baseFont != nil {
+		name = *baseFont
+	}
+	subtype := font.NameEntry("Subtype")
+	if subtype == nil || *subtype == "Type0" || *subtype == "Type3" {
+		return name, true
+	}
+	descriptor, err := ctx.DereferenceDict(font["FontDescriptor"])
+	if err != nil || descriptor == nil {
+		return name, false
+	}
+	for _, key := range []string{"FontFile", "FontFile2", "FontFile3"} {
+		if descriptor[key] != nil {
+			return name, true
+		}
+	}
+	return name, false
+}
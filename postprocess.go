@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Modification of the finished report for things the engines can't do.
+type PostProcessStep func(ctx *model.Context) error
+
+// PostProcessPDF applies the given steps to the PDF at path and replaces
+// the file with the result. The dates of the document info are kept,
+// pdfcpu would set them to the time of writing otherwise.
+func PostProcessPDF(path string, steps ...PostProcessStep) error {
+	if len(steps) == 0 {
+		return nil
+	}
+	ctx, err := api.ReadContextFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s for post-processing: %w", path, err)
+	}
+	for _, step := range steps {
+		if err := step(ctx); err != nil {
+			return err
+		}
+	}
+	dates, err := infoDates(ctx)
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".banana-report-*.pdf")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := api.WriteContext(ctx, tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write post-processed PDF: %w", err)
+	}
+	if err := restoreInfoDates(tmp, dates); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to restore the document info dates: %w", err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Keys of the document info overwritten by pdfcpu on writing.
+var INFO_DATE_KEYS = []string{"CreationDate", "ModDate"}
+
+// Dates of the document info in the format written by pdfcpu.
+func infoDates(ctx *model.Context) (map[string]string, error) {
+	rsl := map[string]string{}
+	if ctx.Info == nil {
+		return rsl, nil
+	}
+	infoDict, err := ctx.DereferenceDict(*ctx.Info)
+	if err != nil || infoDict == nil {
+		return rsl, err
+	}
+	for _, key := range INFO_DATE_KEYS {
+		value, ok := infoDict[key]
+		if !ok {
+			continue
+		}
+		str, err := ctx.DereferenceStringOrHexLiteral(value, model.V10, nil)
+		if err != nil {
+			return nil, err
+		}
+		if date, ok := types.DateTime(str, true); ok {
+			rsl[key] = types.DateString(date)
+		}
+	}
+	return rsl, nil
+}
+
+// Sets the given dates in the document info of the PDF written to file.
+// pdfcpu replaces the dates of a context with the time of writing, only
+// the objects of an incremental update are written as they are. So the
+// document info is appended again with the dates set.
+func restoreInfoDates(file *os.File, dates map[string]string) error {
+	if len(dates) == 0 {
+		return nil
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	ctx, err := api.ReadContext(file, model.NewDefaultConfiguration())
+	if err != nil {
+		return err
+	}
+	if ctx.Info == nil {
+		return fmt.Errorf("no document info written")
+	}
+	infoDict, err := ctx.DereferenceDict(*ctx.Info)
+	if err != nil || infoDict == nil {
+		return err
+	}
+	for _, key := range INFO_DATE_KEYS {
+		if date, ok := dates[key]; ok {
+			infoDict.Update(key, types.StringLiteral(date))
+		}
+	}
+	ctx.Write.Increment = true
+	ctx.Write.Offset = ctx.Read.FileSize
+	ctx.Write.IncrementWithObjNr(ctx.Info.ObjectNumber.Value())
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	return api.WriteIncrement(ctx, file)
+}
+
+// Original receipt file to be attached to a page of the report.
+type OriginalAttachment struct {
+	Page        int
+	Path        string
+	FileName    string
+	Description string
+	// Area of the annotation in PDF user space (x1, y1, x2, y2).
+	Rect [4]float64
+}
+
+// AttachOriginals embeds the original receipt files byte-for-byte. Each file
+// is listed in the EmbeddedFiles name tree and linked to its page with a file
+// attachment annotation as well as an associated file (/AF) entry.
+func AttachOriginals(originals []OriginalAttachment) PostProcessStep {
+	return func(ctx *model.Context) error {
+		if err := ctx.LocateNameTree("EmbeddedFiles", true); err != nil {
+			return err
+		}
+		for i, original := range originals {
+			if err := attachOriginal(ctx, original, fmt.Sprintf("%04d %s", i+1, original.FileName)); err != nil {
+				return fmt.Errorf("failed to attach %s: %w", original.Path, err)
+			}
+		}
+		return nil
+	}
+}
+
+func attachOriginal(ctx *model.Context, original OriginalAttachment, key string) error {
+	streamRef, err := ctx.NewEmbeddedFileStreamDict(original.Path)
+	if err != nil {
+		return err
+	}
+	if err := setEmbeddedFileSubtype(ctx, *streamRef, original.Path); err != nil {
+		return err
+	}
+	fileSpec, err := ctx.NewFileSpecDict(original.FileName, original.FileName, original.Description, *streamRef)
+	if err != nil {
+		return err
+	}
+	fileSpec.InsertName("AFRelationship", "Source")
+	fileSpecRef, err := ctx.IndRefForNewObject(fileSpec)
+	if err != nil {
+		return err
+	}
+	nameMap := model.NameMap{key: []types.Dict{fileSpec}}
+	if err := ctx.Names["EmbeddedFiles"].Add(ctx.XRefTable, key, *fileSpecRef, nameMap, []string{"F", "UF"}); err != nil {
+		return err
+	}
+
+	pageDict, _, _, err := ctx.PageDict(original.Page, false)
+	if err != nil {
+		return err
+	}
+	contents, err := types.EscapedUTF16String(original.Description)
+	if err != nil {
+		return err
+	}
+	appearance, err := attachmentAppearance(ctx, original.Rect)
+	if err != nil {
+		return err
+	}
+	annot := types.Dict{
+		"Type":     types.Name("Annot"),
+		"Subtype":  types.Name("FileAttachment"),
+		"Rect":     types.NewNumberArray(original.Rect[:]...),
+		"Contents": types.StringLiteral(*contents),
+		"FS":       *fileSpecRef,
+		"Name":     types.Name("Paperclip"),
+		"AP":       types.Dict{"N": *appearance},
+		// Print flag, hidden icons would violate PDF/A.
+		"F": types.Integer(4),
+	}
+	annotRef, err := ctx.IndRefForNewObject(annot)
+	if err != nil {
+		return err
+	}
+	if err := appendToPageArray(ctx, pageDict, "Annots", *annotRef); err != nil {
+		return err
+	}
+	return appendToPageArray(ctx, pageDict, "AF", *fileSpecRef)
+}
+
+// Icon of the attachment annotation, a document with a folded corner. Viewers
+// draw their own icon otherwise which PDF/A doesn't allow.
+func attachmentAppearance(ctx *model.Context, rect [4]float64) (*types.IndirectRef, error) {
+	w, h := rect[2]-rect[0], rect[3]-rect[1]
+	fold := min(w, h) / 3
+	content := fmt.Sprintf(
+		"0.4 G 0.8 w 1 1 m %.2f 1 l %.2f %.2f l %.2f %.2f l 1 %.2f l h S %.2f %.2f m %.2f %.2f l %.2f %.2f l S",
+		w-1, w-1, h-1-fold, w-1-fold, h-1, h-1,
+		w-1-fold, h-1, w-1-fold, h-1-fold, w-1, h-1-fold,
+	)
+	stream := types.StreamDict{
+		Dict: types.Dict{
+			"Type":    types.Name("XObject"),
+			"Subtype": types.Name("Form"),
+			"BBox":    types.NewNumberArray(0, 0, w, h),
+		},
+		Content: []byte(content),
+	}
+	if err := stream.Encode(); err != nil {
+		return nil, err
+	}
+	return ctx.IndRefForNewObject(stream)
+}
+
+// Sets the MIME type of an embedded file stream.
+func setEmbeddedFileSubtype(ctx *model.Context, streamRef types.IndirectRef, path string) error {
+	stream, _, err := ctx.DereferenceStreamDict(streamRef)
+	if err != nil || stream == nil {
+		return err
+	}
+	mimeType := "application/pdf"
+	if strings.EqualFold(filepath.Ext(path), ".xml") {
+		mimeType = "text/xml"
+	}
+	stream.InsertName("Subtype", mimeType)
+	return nil
+}
+
+func appendToPageArray(ctx *model.Context, pageDict types.Dict, key string, obj types.Object) error {
+	arr, err := ctx.DereferenceArray(pageDict[key])
+	if err != nil {
+		return err
+	}
+	pageDict.Update(key, append(arr, obj))
+	return nil
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	_ "golang.org/x/image/tiff"
+)
+
+// Amounts are considered equal if they differ by less than this.
+const AMOUNT_TOLERANCE = 0.005
+
+// Payment information of a Swiss QR-bill (Swiss Payment Standards,
+// "Swiss Implementation Guidelines for the QR-bill").
+type QRBill struct {
+	IBAN          string
+	Amount        string // Empty if the amount is left open.
+	Currency      string
+	Creditor      QRBillParty
+	Debtor        QRBillParty
+	ReferenceType string // QRR, SCOR or NON
+	Reference     string
+	Message       string
+	BillingInfo   string
+}
+
+type QRBillParty struct {
+	Name         string
+	AddressLine1 string // Street or first address line.
+	AddressLine2 string // Building number or second address line.
+	PostalCode   string
+	Town         string
+	Country      string
+}
+
+// Parses the payload of a Swiss QR code. Fails if the payload isn't a
+// QR-bill.
+func ParseQRBill(payload string) (*QRBill, error) {
+	lines := strings.Split(strings.ReplaceAll(payload, "\r\n", "\n"), "\n")
+	if len(lines) < 31 || strings.TrimSpace(lines[0]) != "SPC" {
+		return nil, fmt.Errorf("payload is not a Swiss QR-bill")
+	}
+	field := func(i int) string {
+		if i >= len(lines) {
+			return ""
+		}
+		return strings.TrimSpace(lines[i])
+	}
+	party := func(start int) QRBillParty {
+		return QRBillParty{
+			Name:         field(start + 1),
+			AddressLine1: field(start + 2),
+			AddressLine2: field(start + 3),
+			PostalCode:   field(start + 4),
+			Town:         field(start + 5),
+			Country:      field(start + 6),
+		}
+	}
+	return &QRBill{
+		IBAN:          field(3),
+		Creditor:      party(4),
+		Amount:        field(18),
+		Currency:      field(19),
+		Debtor:        party(20),
+		ReferenceType: field(27),
+		Reference:     field(28),
+		Message:       field(29),
+		BillingInfo:   field(31),
+	}, nil
+}
+
+// IBAN in groups of four characters as printed on the payment part.
+func (b QRBill) FmtIBAN() string {
+	return groupChars(b.IBAN, 4)
+}
+
+func (b QRBill) FmtAmount() string {
+	if b.Amount == "" {
+		return fmt.Sprintf("%s (open amount)", b.Currency)
+	}
+	return fmt.Sprintf("%s %s", b.Currency, b.Amount)
+}
+
+func (b QRBill) String() string {
+	rsl := []string{b.FmtIBAN(), b.FmtAmount(), b.Creditor.Name}
+	if b.Reference != "" {
+		rsl = append(rsl, fmt.Sprint("Ref. ", b.Reference))
+	}
+	return strings.Join(rsl, " · ")
+}
+
+// Compares the QR-bill with the booked transactions of the document.
+// Returns a description for each mismatch.
+func (b QRBill) Mismatches(doc Document, base Currency) []string {
+	rsl := []string{}
+	if b.Amount != "" {
+		billAmount, err := strconv.ParseFloat(b.Amount, 64)
+		if err != nil {
+			rsl = append(rsl, fmt.Sprintf("QR-bill amount '%s' is invalid", b.Amount))
+		} else if !b.matchesAmount(doc, base, billAmount) {
+			rsl = append(rsl, fmt.Sprintf("QR-bill amount %s doesn't match the booked amounts", b.FmtAmount()))
+		}
+	}
+
+	suppliers := []string{}
+	for _, tx := range doc.Transactions {
+		for _, supplier := range []string{tx.Cc3, tx.Cc3Des} {
+			if supplier != "" {
+				suppliers = append(suppliers, supplier)
+			}
+		}
+	}
+	if len(suppliers) != 0 && b.Creditor.Name != "" && !matchesAnyName(b.Creditor.Name, suppliers) {
+		rsl = append(rsl, fmt.Sprintf(
+			"QR-bill creditor '%s' doesn't match the booked supplier (KS 3) '%s'",
+			b.Creditor.Name,
+			strings.Join(suppliers, "', '"),
+		))
+	}
+	return rsl
+}
+
+// Whether one of the transactions (or the total of the document) in the
+// currency of the QR-bill equals the given amount. Bookings in another
+// currency than the one of the bill are left out.
+func (b QRBill) matchesAmount(doc Document, base Currency, billAmount float64) bool {
+	total := 0.
+	for _, tx := range doc.Transactions {
+		candidates := []string{tx.Amount, tx.Income, tx.Expenses}
+		if tx.CurrencyCode(base) != normalizeCurrencyCode(b.Currency) {
+			continue
+		}
+		if tx.IsForeignCurrency(base) {
+			candidates = []string{tx.AmountCurrency}
+		}
+		for _, candidate := range candidates {
+			value, err := strconv.ParseFloat(strings.TrimSpace(candidate), 64)
+			if err != nil {
+				continue
+			}
+			if math.Abs(math.Abs(value)-billAmount) < AMOUNT_TOLERANCE {
+				return true
+			}
+			total += value
+			break
+		}
+	}
+	return math.Abs(math.Abs(total)-billAmount) < AMOUNT_TOLERANCE
+}
+
+// DetectQRBills searches each receipt for a Swiss QR code.
+// Found bills are compared with the booked transactions.
+func (d *Dossier) DetectQRBills() {
+	for i := range d.JournalEntries {
+		doc := &d.JournalEntries[i]
+		if !doc.IsValidFile || doc.IsXMLInvoice {
+			continue
+		}
+		bill, err := FindQRBill(doc.AbsolutePath)
+		if err != nil {
+			fmt.Printf("%s: failed to search for QR-bill: %s\n", doc.Path, err)
+			continue
+		}
+		if bill == nil {
+			continue
+		}
+		doc.QRBill = bill
+		doc.QRBillMismatches = bill.Mismatches(*doc, d.Currency())
+		for _, mismatch := range doc.QRBillMismatches {
+			fmt.Printf("%s: %s\n", doc.Path, mismatch)
+		}
+	}
+}
+
+// FindQRBill searches the pages of the PDF at the given path for a Swiss
+// QR-bill, first in the embedded images and then in the rendered vector
+// graphics of the page (QR codes of generated bills are usually drawn as
+// paths). Returns nil if the receipt contains no QR-bill.
+func FindQRBill(path string) (*QRBill, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Page images are only collected during optimization.
+	conf := model.NewDefaultConfiguration()
+	conf.Cmd = model.EXTRACTIMAGES
+	ctx, err := api.ReadValidateAndOptimize(file, conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	for page := 1; page <= ctx.PageCount; page++ {
+		bill, err := findQRBillOnPage(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		if bill != nil {
+			return bill, nil
+		}
+	}
+	return nil, nil
+}
+
+func findQRBillOnPage(ctx *model.Context, page int) (bill *QRBill, err error) {
+	defer func() {
+		// pdfcpu panics on some image encodings (e.g. 16 bit grayscale).
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to search page %d: '%s'", page, r)
+		}
+	}()
+	images, err := pdfcpu.ExtractPageImages(ctx, page, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract images of page %d: %w", page, err)
+	}
+	for _, img := range images {
+		decoded, _, err := image.Decode(img)
+		if err != nil {
+			// Unsupported image formats (e.g. JPEG 2000) are skipped.
+			continue
+		}
+		if bill := decodeQRBill(decoded); bill != nil {
+			return bill, nil
+		}
+	}
+	rendered, err := renderPageVectors(ctx, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render page %d: %w", page, err)
+	}
+	if rendered == nil {
+		return nil, nil
+	}
+	return decodeQRBill(rendered), nil
+}
+
+// Decodes the QR code in the image, nil if there is none or it isn't a
+// QR-bill.
+func decodeQRBill(img image.Image) *QRBill {
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return nil
+	}
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER:    true,
+		gozxing.DecodeHintType_CHARACTER_SET: "UTF-8",
+	}
+	result, err := qrcode.NewQRCodeReader().Decode(bitmap, hints)
+	if err != nil {
+		return nil
+	}
+	bill, err := ParseQRBill(result.GetText())
+	if err != nil {
+		return nil
+	}
+	return bill
+}
+
+// Reports whether name and one of the candidates contain each other after
+// removing case, punctuation and whitespace.
+func matchesAnyName(name string, candidates []string) bool {
+	normalized := normalizeName(name)
+	for _, candidate := range candidates {
+		other := normalizeName(candidate)
+		if other == "" {
+			continue
+		}
+		if strings.Contains(normalized, other) || strings.Contains(other, normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeName(name string) string {
+	var builder strings.Builder
+	for _, char := range strings.ToLower(name) {
+		if unicode.IsLetter(char) || unicode.IsDigit(char) {
+			builder.WriteRune(char)
+		}
+	}
+	return builder.String()
+}
+
+func groupChars(str string, size int) string {
+	var builder strings.Builder
+	for i, char := range []rune(strings.ReplaceAll(str, " ", "")) {
+		if i != 0 && i%size == 0 {
+			builder.WriteRune(' ')
+		}
+		builder.WriteRune(char)
+	}
+	return builder.String()
+}
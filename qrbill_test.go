@@ -0,0 +1,77 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/boombuler/barcode/qr"
+	"github.com/go-pdf/fpdf"
+)
+
+const testQRBillPayload = "SPC\n0200\n1\nCH4431999123000889012\nS\nMuster Krankenkasse\nMusterstrasse\n12\n8000\nZürich\nCH\n\n\n\n\n\n\n\n123.45\nCHF\nS\nSarah Beispiel\nMustergasse\n1\n3600\nThun\nCH\nQRR\n210000000003139471430009017\nRechnung 2023-17\nEPD\n"
+
+// Writes a PDF with the QR code of the payload drawn as one rectangle per
+// module, like most generated QR-bills.
+func writeVectorQRBill(t *testing.T, payload string) string {
+	t.Helper()
+	code, err := qr.Encode(payload, qr.M, qr.Unicode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	size := code.Bounds().Dx()
+	module := 46. / float64(size)
+	pdf.SetFillColor(0, 0, 0)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			r, _, _, _ := code.At(x, y).RGBA()
+			if r == 0 {
+				pdf.Rect(67+float64(x)*module, 209+float64(y)*module, module, module, "F")
+			}
+		}
+	}
+	path := filepath.Join(t.TempDir(), "bill.pdf")
+	if err := pdf.OutputFileAndClose(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFindQRBillVector(t *testing.T) {
+	bill, err := FindQRBill(writeVectorQRBill(t, testQRBillPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bill == nil {
+		t.Fatal("no QR-bill found in vector graphics")
+	}
+	if bill.IBAN != "CH4431999123000889012" || bill.Amount != "123.45" || bill.Creditor.Name != "Muster Krankenkasse" {
+		t.Errorf("unexpected bill %+v", bill)
+	}
+}
+
+func TestQRBillMatchesAmount(t *testing.T) {
+	chf := LookupCurrency("CHF")
+	bill, err := ParseQRBill(strings.Replace(testQRBillPayload, "\nCHF\n", "\nEUR\n", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		transactions Transactions
+		want         bool
+	}{
+		{"base currency booking ignored for foreign bill", Transactions{{Amount: "123.45"}}, false},
+		{"booking in bill currency", Transactions{{Amount: "118.50", AmountCurrency: "123.45", ExchangeCurrency: "EUR"}}, true},
+		{"booking in other foreign currency", Transactions{{Amount: "118.50", AmountCurrency: "123.45", ExchangeCurrency: "USD"}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := bill.matchesAmount(Document{Transactions: test.transactions}, chf, 123.45); got != test.want {
+				t.Errorf("matchesAmount = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Payload presets of the QR code in the header.
+const (
+	QR_PAYLOAD_PATH = "{path}"
+	QR_PAYLOAD_JSON = "json"
+)
+
+var qrPlaceholderPattern = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// SetQRPayloads renders the payload of the header QR code of each document.
+// The template may contain the placeholders {path}, {abspath}, {file},
+// {title}, {ident}, {unique}, {date}, {amount} and {currency}. Values are
+// URL-escaped if the template is a URL, as path or as query value depending
+// on whether they come before or after the "?". The preset "json" encodes
+// all values as JSON object.
+func (d *Dossier) SetQRPayloads(template string) error {
+	if template == "" {
+		template = QR_PAYLOAD_PATH
+	}
+	if template != QR_PAYLOAD_JSON {
+		for _, match := range qrPlaceholderPattern.FindAllStringSubmatch(template, -1) {
+			if _, ok := d.qrPayloadValues(Document{})[match[1]]; !ok {
+				return fmt.Errorf("unknown QR payload placeholder '%s'", match[0])
+			}
+		}
+	}
+	for i := range d.JournalEntries {
+		doc := &d.JournalEntries[i]
+		values := d.qrPayloadValues(*doc)
+		if template == QR_PAYLOAD_JSON {
+			data, err := json.Marshal(values)
+			if err != nil {
+				return err
+			}
+			doc.QRPayload = string(data)
+			continue
+		}
+		doc.QRPayload = renderQRPayload(template, values)
+	}
+	return nil
+}
+
+// Replaces the placeholders of the template with the given values.
+func renderQRPayload(template string, values map[string]string) string {
+	isURL := strings.Contains(template, "://")
+	queryStart := strings.Index(template, "?")
+	var builder strings.Builder
+	last := 0
+	for _, match := range qrPlaceholderPattern.FindAllStringSubmatchIndex(template, -1) {
+		builder.WriteString(template[last:match[0]])
+		value := values[template[match[2]:match[3]]]
+		switch {
+		case !isURL:
+			builder.WriteString(value)
+		case queryStart != -1 && match[0] > queryStart:
+			builder.WriteString(url.QueryEscape(value))
+		default:
+			builder.WriteString(escapeURLPath(value))
+		}
+		last = match[1]
+	}
+	builder.WriteString(template[last:])
+	return builder.String()
+}
+
+// Escapes each segment of the path, the slashes are kept.
+func escapeURLPath(path string) string {
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// Values of the QR payload placeholders. Date and amount are taken from the
+// first transaction of the document, doc numbers and unique ids are joined
+// by commas.
+func (d Dossier) qrPayloadValues(doc Document) map[string]string {
+	uniques := []string{}
+	for _, tx := range doc.Transactions {
+		uniques = append(uniques, tx.Unique)
+	}
+	rsl := map[string]string{
+		"path":     doc.Path,
+		"abspath":  doc.AbsolutePath,
+		"file":     filepath.Base(doc.Path),
+		"title":    doc.Title(),
+		"ident":    strings.Join(doc.Idents(), ","),
+		"unique":   strings.Join(uniques, ","),
+		"date":     "",
+		"amount":   "",
+		"currency": d.BaseCurrency,
+	}
+	if len(doc.Transactions) != 0 {
+		tx := doc.Transactions[0]
+		rsl["date"] = tx.Date
+		rsl["amount"] = tx.Amount
+		if tx.IsForeignCurrency(d.Currency()) {
+			rsl["amount"] = tx.AmountCurrency
+			rsl["currency"] = tx.CurrencyCode(d.Currency())
+		}
+		// Cash basis accounting.
+		for _, amount := range []string{tx.Income, tx.Expenses} {
+			if rsl["amount"] == "" {
+				rsl["amount"] = amount
+			}
+		}
+	}
+	return rsl
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/image/vector"
+)
+
+// Resolution of the rendered pages, a module of the QR code of a QR-bill
+// (46 mm, up to 117 modules) is about three pixels wide.
+const QR_RENDER_DPI = 200
+
+// Form XObjects nested deeper than this aren't rendered.
+const MAX_FORM_DEPTH = 8
+
+// renderPageVectors rasterises the filled paths of the page (including the
+// ones of form XObjects) into a grayscale image. Text, images and strokes
+// aren't rendered, it's only meant for QR codes drawn as vector graphics.
+func renderPageVectors(ctx *model.Context, page int) (*image.Gray, error) {
+	pageDict, _, attrs, err := ctx.PageDict(page, false)
+	if err != nil {
+		return nil, err
+	}
+	box := attrs.MediaBox
+	if attrs.CropBox != nil {
+		box = attrs.CropBox
+	}
+	if box == nil {
+		return nil, fmt.Errorf("page %d has no media box", page)
+	}
+	content, err := ctx.PageContent(pageDict, page)
+	if err == model.ErrNoContent {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	scale := QR_RENDER_DPI / 72.
+	width, height := int(math.Ceil(box.Width()*scale)), int(math.Ceil(box.Height()*scale))
+	dst := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	renderer := &vectorRenderer{
+		ctx:        ctx,
+		dst:        dst,
+		rasterizer: vector.NewRasterizer(0, 0),
+	}
+	state := graphicsState{
+		ctm: matrix{scale, 0, 0, -scale, -box.LL.X * scale, box.UR.Y * scale},
+	}
+	renderer.render(content, attrs.Resources, state, 0)
+	return dst, nil
+}
+
+// Affine transformation [a b c d e f] as used by the cm operator.
+type matrix [6]float64
+
+// Transformation m followed by n.
+func (m matrix) then(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func (m matrix) apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]
+}
+
+type graphicsState struct {
+	ctm matrix
+	// Fill color as gray level between 0 (black) and 1 (white).
+	fill float64
+}
+
+// Segment of a path in device space.
+type pathSegment struct {
+	op     byte // m (move), l (line), c (curve), h (close)
+	points []float64
+}
+
+type vectorRenderer struct {
+	ctx        *model.Context
+	dst        *image.Gray
+	rasterizer *vector.Rasterizer
+	path       []pathSegment
+}
+
+func (r *vectorRenderer) render(content []byte, resources types.Dict, state graphicsState, depth int) {
+	stack := []graphicsState{}
+	operands := []float64{}
+	names := []string{}
+	// Current point in user space for the v operator.
+	curX, curY := 0., 0.
+	point := func(x, y float64) []float64 {
+		dx, dy := state.ctm.apply(x, y)
+		return []float64{dx, dy}
+	}
+	arg := func(i int) float64 {
+		return operands[len(operands)-i]
+	}
+	lexer := contentLexer{content: content}
+	for {
+		token, kind := lexer.next()
+		if kind == tokenEOF {
+			return
+		}
+		switch kind {
+		case tokenNumber:
+			value, _ := strconv.ParseFloat(token, 64)
+			operands = append(operands, value)
+			continue
+		case tokenName:
+			names = append(names, token)
+			continue
+		case tokenOther:
+			continue
+		}
+		switch token {
+		case "q":
+			stack = append(stack, state)
+		case "Q":
+			if len(stack) != 0 {
+				state = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(operands) >= 6 {
+				state.ctm = matrix{arg(6), arg(5), arg(4), arg(3), arg(2), arg(1)}.then(state.ctm)
+			}
+		case "m", "l":
+			if len(operands) >= 2 {
+				curX, curY = arg(2), arg(1)
+				r.path = append(r.path, pathSegment{token[0], point(curX, curY)})
+			}
+		case "c", "v", "y":
+			points := []float64{}
+			switch {
+			case token == "c" && len(operands) >= 6:
+				points = append(append(point(arg(6), arg(5)), point(arg(4), arg(3))...), point(arg(2), arg(1))...)
+			case token == "v" && len(operands) >= 4:
+				points = append(append(point(curX, curY), point(arg(4), arg(3))...), point(arg(2), arg(1))...)
+			case token == "y" && len(operands) >= 4:
+				points = append(append(point(arg(4), arg(3)), point(arg(2), arg(1))...), point(arg(2), arg(1))...)
+			}
+			if len(points) != 0 {
+				curX, curY = arg(2), arg(1)
+				r.path = append(r.path, pathSegment{'c', points})
+			}
+		case "h":
+			r.path = append(r.path, pathSegment{'h', nil})
+		case "re":
+			if len(operands) >= 4 {
+				x, y, w, h := arg(4), arg(3), arg(2), arg(1)
+				r.path = append(r.path,
+					pathSegment{'m', point(x, y)},
+					pathSegment{'l', point(x+w, y)},
+					pathSegment{'l', point(x+w, y+h)},
+					pathSegment{'l', point(x, y+h)},
+					pathSegment{'h', nil},
+				)
+				curX, curY = x, y
+			}
+		case "f", "F", "f*", "B", "B*", "b", "b*":
+			r.fill(state.fill)
+			r.path = nil
+		case "n", "S", "s":
+			r.path = nil
+		case "g":
+			if len(operands) >= 1 {
+				state.fill = arg(1)
+			}
+		case "rg":
+			if len(operands) >= 3 {
+				state.fill = rgbGray(arg(3), arg(2), arg(1))
+			}
+		case "k":
+			if len(operands) >= 4 {
+				state.fill = cmykGray(arg(4), arg(3), arg(2), arg(1))
+			}
+		case "cs":
+			state.fill = 0
+		case "sc", "scn":
+			switch len(operands) {
+			case 1:
+				state.fill = arg(1)
+			case 3:
+				state.fill = rgbGray(arg(3), arg(2), arg(1))
+			case 4:
+				state.fill = cmykGray(arg(4), arg(3), arg(2), arg(1))
+			}
+		case "Do":
+			if len(names) != 0 && depth < MAX_FORM_DEPTH {
+				r.renderForm(names[len(names)-1], resources, state, depth)
+			}
+		case "BI":
+			lexer.skipInlineImage()
+		}
+		operands = operands[:0]
+		names = names[:0]
+	}
+}
+
+// Renders the form XObject with the given name, images are skipped.
+func (r *vectorRenderer) renderForm(name string, resources types.Dict, state graphicsState, depth int) {
+	xObjects, err := r.ctx.DereferenceDict(resources["XObject"])
+	if err != nil || xObjects == nil {
+		return
+	}
+	form, _, err := r.ctx.DereferenceStreamDict(xObjects[name])
+	if err != nil || form == nil {
+		return
+	}
+	if subtype := form.Dict.NameEntry("Subtype"); subtype == nil || *subtype != "Form" {
+		return
+	}
+	if err := form.Decode(); err != nil {
+		return
+	}
+	if values, err := r.ctx.DereferenceArray(form.Dict["Matrix"]); err == nil && len(values) == 6 {
+		m := matrix{}
+		for i, value := range values {
+			m[i], _ = r.ctx.DereferenceNumber(value)
+		}
+		state.ctm = m.then(state.ctm)
+	}
+	formResources, err := r.ctx.DereferenceDict(form.Dict["Resources"])
+	if err != nil || formResources == nil {
+		formResources = resources
+	}
+	r.render(form.Content, formResources, state, depth+1)
+}
+
+// Fills the current path with the given gray level. The rasterizer only
+// covers the bounding box of the path as most paths are small.
+func (r *vectorRenderer) fill(gray float64) {
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, segment := range r.path {
+		for i := 0; i+1 < len(segment.points); i += 2 {
+			minX, maxX = math.Min(minX, segment.points[i]), math.Max(maxX, segment.points[i])
+			minY, maxY = math.Min(minY, segment.points[i+1]), math.Max(maxY, segment.points[i+1])
+		}
+	}
+	bounds := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+	bounds = bounds.Intersect(r.dst.Bounds())
+	if bounds.Empty() {
+		return
+	}
+	offsetX, offsetY := float32(bounds.Min.X), float32(bounds.Min.Y)
+	r.rasterizer.Reset(bounds.Dx(), bounds.Dy())
+	isOpen := false
+	for _, segment := range r.path {
+		p := make([]float32, len(segment.points))
+		for i, value := range segment.points {
+			p[i] = float32(value)
+			if i%2 == 0 {
+				p[i] -= offsetX
+			} else {
+				p[i] -= offsetY
+			}
+		}
+		switch segment.op {
+		case 'm':
+			if isOpen {
+				r.rasterizer.ClosePath()
+			}
+			r.rasterizer.MoveTo(p[0], p[1])
+			isOpen = true
+		case 'l':
+			r.rasterizer.LineTo(p[0], p[1])
+		case 'c':
+			r.rasterizer.CubeTo(p[0], p[1], p[2], p[3], p[4], p[5])
+		case 'h':
+			if isOpen {
+				r.rasterizer.ClosePath()
+				isOpen = false
+			}
+		}
+	}
+	if isOpen {
+		r.rasterizer.ClosePath()
+	}
+	level := uint8(math.Round(math.Max(0, math.Min(1, gray)) * 255))
+	r.rasterizer.Draw(r.dst, bounds, image.NewUniform(color.Gray{Y: level}), image.Point{})
+}
+
+func rgbGray(red, green, blue float64) float64 {
+	return .299*red + .587*green + .114*blue
+}
+
+func cmykGray(cyan, magenta, yellow, black float64) float64 {
+	return 1 - math.Min(1, .3*cyan+.59*magenta+.11*yellow+black)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenName
+	tokenOperator
+	// Strings, arrays and dictionaries, their content isn't needed.
+	tokenOther
+)
+
+// Splits a content stream into tokens.
+type contentLexer struct {
+	content []byte
+	pos     int
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) != -1
+}
+
+func (l *contentLexer) next() (string, tokenKind) {
+	for l.pos < len(l.content) {
+		c := l.content[l.pos]
+		switch {
+		case isWhitespace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.content) && l.content[l.pos] != '\n' && l.content[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '(':
+			_, end := readLiteralString(l.content, l.pos)
+			l.pos = end + 1
+			return "", tokenOther
+		case c == '<':
+			if l.pos+1 < len(l.content) && l.content[l.pos+1] == '<' {
+				l.pos += 2
+				return "<<", tokenOther
+			}
+			end := bytes.IndexByte(l.content[l.pos:], '>')
+			if end == -1 {
+				l.pos = len(l.content)
+			} else {
+				l.pos += end + 1
+			}
+			return "", tokenOther
+		case c == '>' || c == '[' || c == ']' || c == '{' || c == '}' || c == ')':
+			l.pos++
+			return string(c), tokenOther
+		case c == '/':
+			start := l.pos + 1
+			l.pos++
+			for l.pos < len(l.content) && !isWhitespace(l.content[l.pos]) && !isDelimiter(l.content[l.pos]) {
+				l.pos++
+			}
+			return string(l.content[start:l.pos]), tokenName
+		default:
+			start := l.pos
+			for l.pos < len(l.content) && !isWhitespace(l.content[l.pos]) && !isDelimiter(l.content[l.pos]) {
+				l.pos++
+			}
+			token := string(l.content[start:l.pos])
+			if _, err := strconv.ParseFloat(token, 64); err == nil {
+				return token, tokenNumber
+			}
+			return token, tokenOperator
+		}
+	}
+	return "", tokenEOF
+}
+
+// Skips the parameters and the data of an inline image up to and including
+// the EI operator.
+func (l *contentLexer) skipInlineImage() {
+	for {
+		token, kind := l.next()
+		if kind == tokenEOF {
+			return
+		}
+		if kind == tokenOperator && token == "ID" {
+			break
+		}
+	}
+	for l.pos+2 <= len(l.content) {
+		if l.content[l.pos] == 'E' && l.content[l.pos+1] == 'I' &&
+			isWhitespace(l.content[l.pos-1]) &&
+			(l.pos+2 == len(l.content) || isWhitespace(l.content[l.pos+2])) {
+			l.pos += 2
+			return
+		}
+		l.pos++
+	}
+	l.pos = len(l.content)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// Parameters of the detection of white margins on scanned receipts.
+const (
+	// Pixels with a lower (8 bit) luminance count as content.
+	TRIM_LUMINANCE = 208
+	// Share of content pixels a row or column needs to be part of the
+	// content, ignores dust and scanner noise.
+	TRIM_MIN_CONTENT = 0.005
+	// Margin kept around the content, as share of the page size.
+	TRIM_PADDING = 0.02
+	// Trimming is skipped if it would gain less than this share of the page.
+	TRIM_MIN_GAIN = 0.05
+)
+
+// Area of a page as shares of the width and height of the page, measured
+// from the top left corner.
+type PageArea struct {
+	Left   float64
+	Top    float64
+	Right  float64
+	Bottom float64
+}
+
+// Whole page.
+var FULL_PAGE = PageArea{0, 0, 1, 1}
+
+func (a PageArea) Width() float64 {
+	return a.Right - a.Left
+}
+
+func (a PageArea) Height() float64 {
+	return a.Bottom - a.Top
+}
+
+// Returns the area after turning the page clockwise by the given multiple of
+// 90 degrees.
+func (a PageArea) rotate(degrees int) PageArea {
+	switch ((degrees % 360) + 360) % 360 {
+	case 90:
+		return PageArea{1 - a.Bottom, a.Left, 1 - a.Top, a.Right}
+	case 180:
+		return PageArea{1 - a.Right, 1 - a.Bottom, 1 - a.Left, 1 - a.Top}
+	case 270:
+		return PageArea{a.Top, 1 - a.Right, a.Bottom, 1 - a.Left}
+	}
+	return a
+}
+
+// Page of a receipt as shown by a PDF viewer.
+type ReceiptPage struct {
+	// Size of the crop box (the media box if there is none) in pt, width
+	// and height are swapped for pages rotated by 90 or 270 degrees.
+	Width  float64
+	Height float64
+	// Whether the page has a crop box of its own.
+	HasCropBox bool
+	// Clockwise rotation (/Rotate) in degrees.
+	Rotation int
+	// Part of the page shown in the report, the whole page unless the white
+	// margins of a scanned page are trimmed.
+	Content PageArea
+}
+
+// GetPDFPages returns the visible size and the rotation of all pages of the
+// PDF at the given path.
+func GetPDFPages(path string) ([]ReceiptPage, error) {
+	ctx, err := api.ReadContextFile(path)
+	if err != nil {
+		return nil, err
+	}
+	boundaries, err := ctx.PageBoundaries(nil)
+	if err != nil {
+		return nil, err
+	}
+	rsl := make([]ReceiptPage, len(boundaries))
+	for i, boundary := range boundaries {
+		box := boundary.CropBox()
+		rsl[i] = ReceiptPage{
+			Width:      box.Width(),
+			Height:     box.Height(),
+			HasCropBox: boundary.Crop != nil && boundary.Crop.Rect != nil,
+			Rotation:   boundary.Rot,
+			Content:    FULL_PAGE,
+		}
+		if boundary.Rot%180 != 0 {
+			rsl[i].Width, rsl[i].Height = rsl[i].Height, rsl[i].Width
+		}
+	}
+	return rsl, nil
+}
+
+// Page of the document with the given number (starting at 1). Falls back to
+// an A4 portrait page if the geometry couldn't be read.
+func (d Document) Page(page int) ReceiptPage {
+	if page < 1 || page > len(d.Pages) {
+		return ReceiptPage{Width: 595.28, Height: 841.89, Content: FULL_PAGE}
+	}
+	return d.Pages[page-1]
+}
+
+// TrimReceipts detects the white margins of scanned receipt pages and limits
+// the shown content of these pages to the area within.
+func (d *Dossier) TrimReceipts() {
+	for i := range d.JournalEntries {
+		doc := &d.JournalEntries[i]
+		if !doc.IsValidFile || doc.IsXMLInvoice || len(doc.Pages) == 0 {
+			continue
+		}
+		if err := doc.trimPages(); err != nil {
+			fmt.Printf("%s: failed to trim receipt: %s\n", doc.Path, err)
+		}
+	}
+}
+
+func (d *Document) trimPages() error {
+	file, err := os.Open(d.AbsolutePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Page images are only collected during optimization.
+	conf := model.NewDefaultConfiguration()
+	conf.Cmd = model.EXTRACTIMAGES
+	ctx, err := api.ReadValidateAndOptimize(file, conf)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF: %w", err)
+	}
+	for page := 1; page <= ctx.PageCount && page <= len(d.Pages); page++ {
+		content, err := scannedContentArea(ctx, page)
+		if err != nil {
+			return err
+		}
+		d.Pages[page-1].Content = content.rotate(d.Pages[page-1].Rotation)
+	}
+	return nil
+}
+
+// Content area of a scanned page (one image covering the page) in the
+// orientation of the image. Returns the whole page for all other pages.
+func scannedContentArea(ctx *model.Context, page int) (area PageArea, err error) {
+	defer func() {
+		// pdfcpu panics on some image encodings (e.g. 16 bit grayscale).
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to extract images of page %d: '%s'", page, r)
+		}
+	}()
+	images, err := pdfcpu.ExtractPageImages(ctx, page, false)
+	if err != nil {
+		return FULL_PAGE, fmt.Errorf("failed to extract images of page %d: %w", page, err)
+	}
+	if len(images) != 1 {
+		return FULL_PAGE, nil
+	}
+	for _, img := range images {
+		decoded, _, err := image.Decode(img)
+		if err != nil {
+			// Unsupported image formats (e.g. JPEG 2000) are not trimmed.
+			return FULL_PAGE, nil
+		}
+		area = contentArea(decoded)
+	}
+	if area.Width()*area.Height() > 1-TRIM_MIN_GAIN {
+		return FULL_PAGE, nil
+	}
+	return area, nil
+}
+
+// Bounding box of the non-white pixels of the image plus padding. Large
+// images are sampled.
+func contentArea(img image.Image) PageArea {
+	bounds := img.Bounds()
+	step := max(1, max(bounds.Dx(), bounds.Dy())/1000)
+	columns := make([]int, (bounds.Dx()+step-1)/step)
+	rows := make([]int, (bounds.Dy()+step-1)/step)
+	for y := 0; y < len(rows); y++ {
+		for x := 0; x < len(columns); x++ {
+			pixel := img.At(bounds.Min.X+x*step, bounds.Min.Y+y*step)
+			if color.GrayModel.Convert(pixel).(color.Gray).Y < TRIM_LUMINANCE {
+				columns[x]++
+				rows[y]++
+			}
+		}
+	}
+	left, right, ok := contentRange(columns, len(rows))
+	if !ok {
+		return FULL_PAGE
+	}
+	top, bottom, _ := contentRange(rows, len(columns))
+	return PageArea{
+		Left:   max(0, left-TRIM_PADDING),
+		Top:    max(0, top-TRIM_PADDING),
+		Right:  min(1, right+TRIM_PADDING),
+		Bottom: min(1, bottom+TRIM_PADDING),
+	}
+}
+
+// Returns the first and last line with content as shares of the number of
+// lines.
+func contentRange(counts []int, length int) (float64, float64, bool) {
+	threshold := int(float64(length) * TRIM_MIN_CONTENT)
+	first, last := -1, -1
+	for i, count := range counts {
+		if count > threshold {
+			if first == -1 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first == -1 {
+		return 0, 1, false
+	}
+	return float64(first) / float64(len(counts)), float64(last+1) / float64(len(counts)), true
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Receipts recognised by the account number need at least this number of
+// bookings, a single payment from the account is an ordinary receipt.
+const STATEMENT_MIN_BOOKINGS = 2
+
+// All bookings of the journal table, unlike JournalFromTable also the ones
+// without a linked receipt.
+func BookingsFromTable(table Table) Transactions {
+	rsl := []Transaction{}
+	for _, row := range table.RowList {
+		if row.Section == "*" || row.Date == "" {
+			continue
+		}
+		rsl = append(rsl, TransactionFromRow(row))
+	}
+	return rsl
+}
+
+// Statement of an account (e.g. a bank statement) linked from the bookings
+// of the statement period.
+type Statement struct {
+	Account     string
+	Description string
+	// ISO 4217 code of the currency of the account.
+	Currency string
+	// Dates of the first and last booking linked to the statement.
+	From time.Time
+	To   time.Time
+	// Balance at the start of From and the end of To computed from the
+	// opening balance of the account and the journal.
+	Opening float64
+	Closing float64
+	// All bookings against the account within the period.
+	Entries []StatementEntry
+}
+
+// Booking against the account of a statement.
+type StatementEntry struct {
+	Booking Transaction
+	// In the currency of the account, positive for debits (increase of an
+	// asset).
+	Amount float64
+	// Balance after the booking.
+	Balance float64
+	// Whether the booking links to the statement receipt.
+	IsLinked bool
+}
+
+func (s Statement) FmtPeriod() string {
+	return fmt.Sprint(s.From.Format(DATE_FORMAT), " – ", s.To.Format(DATE_FORMAT))
+}
+
+// DetectStatements marks the receipts whose path matches one of the given
+// patterns (e.g. "*/bank/*.pdf") or whose bookings all book against one of
+// the given accounts as account statements and computes the balances and
+// bookings of the statement period. Only the opening balances and classes
+// of the accounts table are used.
+func (d *Dossier) DetectStatements(patterns, accounts []string, cashBasisAccounting bool) error {
+	if len(d.Accounts) == 0 {
+		return fmt.Errorf("account statements need the accounts table, which is missing in the export")
+	}
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid statement pattern '%s': %w", pattern, err)
+		}
+	}
+	for _, number := range accounts {
+		if _, ok := d.Accounts[number]; !ok {
+			return fmt.Errorf("statement account '%s' is not in the accounts table", number)
+		}
+	}
+	for i := range d.JournalEntries {
+		doc := &d.JournalEntries[i]
+		if doc.IsXMLInvoice {
+			continue
+		}
+		account := ""
+		if matchesAnyPattern(doc.Path, patterns) {
+			account = d.statementAccount(*doc, accounts, cashBasisAccounting)
+			if account == "" {
+				fmt.Printf("%s: failed to determine the account of the statement\n", doc.Path)
+				continue
+			}
+		} else if len(doc.Transactions) >= STATEMENT_MIN_BOOKINGS {
+			account = commonAccount(*doc, accounts, cashBasisAccounting)
+		}
+		if account == "" {
+			continue
+		}
+		statement, err := d.statement(*doc, account, cashBasisAccounting)
+		if err != nil {
+			fmt.Printf("%s: failed to compute statement: %s\n", doc.Path, err)
+			continue
+		}
+		doc.Statement = statement
+	}
+	return nil
+}
+
+// Whether the path or the file name matches one of the patterns.
+func matchesAnyPattern(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		for _, name := range []string{path, filepath.Base(path)} {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Accounts booked by the transaction.
+func (t Transaction) bookedAccounts(cashBasisAccounting bool) []string {
+	if cashBasisAccounting {
+		return []string{t.Account}
+	}
+	return []string{t.AccountDebit, t.AccountCredit}
+}
+
+// Account of the given statement receipt: the one of the given accounts
+// booked by most transactions, or if none is given the balance sheet
+// account booked most often.
+func (d Dossier) statementAccount(doc Document, accounts []string, cashBasisAccounting bool) string {
+	counts := map[string]int{}
+	rsl := ""
+	for _, tx := range doc.Transactions {
+		for _, account := range tx.bookedAccounts(cashBasisAccounting) {
+			if account == "" {
+				continue
+			}
+			if len(accounts) != 0 && !slices.Contains(accounts, account) {
+				continue
+			}
+			if len(accounts) == 0 && !d.Accounts[account].IsBalanceSheetAccount() {
+				continue
+			}
+			counts[account]++
+			if rsl == "" || counts[account] > counts[rsl] {
+				rsl = account
+			}
+		}
+	}
+	return rsl
+}
+
+// The one of the given accounts booked by all transactions of the document.
+func commonAccount(doc Document, accounts []string, cashBasisAccounting bool) string {
+	for _, account := range accounts {
+		all := true
+		for _, tx := range doc.Transactions {
+			if !slices.Contains(tx.bookedAccounts(cashBasisAccounting), account) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return account
+		}
+	}
+	return ""
+}
+
+func (d Dossier) statement(doc Document, number string, cashBasisAccounting bool) (*Statement, error) {
+	account, ok := d.Accounts[number]
+	if !ok {
+		return nil, fmt.Errorf("account '%s' is not in the accounts table", number)
+	}
+	rsl := &Statement{
+		Account:     number,
+		Description: account.Description,
+		Currency:    d.BaseCurrency,
+	}
+	if account.Currency != "" {
+		rsl.Currency = normalizeCurrencyCode(account.Currency)
+	}
+	for _, tx := range doc.Transactions {
+		date, err := tx.ParsedDate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid date '%s' of booking %s", tx.Date, tx.Unique)
+		}
+		if rsl.From.IsZero() || date.Before(rsl.From) {
+			rsl.From = date
+		}
+		if date.After(rsl.To) {
+			rsl.To = date
+		}
+	}
+	isForeign := rsl.Currency != d.BaseCurrency
+	opening := account.Opening
+	if isForeign && account.OpeningCurrency != "" {
+		opening = account.OpeningCurrency
+	}
+	if opening != "" {
+		value, err := strconv.ParseFloat(strings.TrimSpace(opening), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid opening balance '%s' of account %s", opening, number)
+		}
+		rsl.Opening = value
+	}
+
+	bookings := slices.Clone(d.bookings)
+	// The journal is ordered by entry, the balance needs the booking order.
+	slices.SortStableFunc(bookings, func(a, b Transaction) int {
+		return strings.Compare(a.Date, b.Date)
+	})
+	for _, tx := range bookings {
+		date, err := tx.ParsedDate()
+		if err != nil || date.After(rsl.To) {
+			continue
+		}
+		amount, ok, err := tx.accountAmount(number, isForeign, cashBasisAccounting)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if date.Before(rsl.From) {
+			rsl.Opening += amount
+			continue
+		}
+		rsl.Entries = append(rsl.Entries, StatementEntry{
+			Booking:  tx,
+			Amount:   amount,
+			IsLinked: strings.TrimSpace(tx.Path) == doc.Path,
+		})
+	}
+	balance := rsl.Opening
+	for i := range rsl.Entries {
+		balance += rsl.Entries[i].Amount
+		rsl.Entries[i].Balance = balance
+	}
+	rsl.Closing = balance
+	return rsl, nil
+}
+
+// Amount the transaction books against the account, positive for debits.
+// Foreign currency accounts use the amount in the currency of the booking.
+// Returns false if the transaction doesn't book against the account.
+func (t Transaction) accountAmount(account string, isForeign, cashBasisAccounting bool) (float64, bool, error) {
+	if cashBasisAccounting {
+		if t.Account != account {
+			return 0, false, nil
+		}
+		amount, err := t.BaseAmount(true)
+		return amount, err == nil, err
+	}
+	isDebit, isCredit := t.AccountDebit == account, t.AccountCredit == account
+	if isDebit == isCredit {
+		return 0, false, nil
+	}
+	amount, err := t.BaseAmount(false)
+	if isForeign {
+		amount, err = t.CurrencyAmount()
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if isCredit {
+		amount = -amount
+	}
+	return amount, true, nil
+}
@@ -1,26 +1,85 @@
 #import "@preview/codetastic:0.2.2": qrcode
 
 #let GENERAL_STROKE = 0.8pt
-#let HEADER_SIZE = 21pt
 #let HEADER_SPACING = 3.5mm
 #let DEBUG = sys.inputs.at("debug", default: false)
 #let DOSSIER_FILE = sys.inputs.at("input", default: "dossier.json")
+#let INFO_FILE = sys.inputs.at("info", default: "document-info.json")
+// Transaction tables of the documents in the order of the documents.
+#let TABLES_FILE = sys.inputs.at("tables", default: "tables.json")
+#let ATTACH_ORIGINALS = sys.inputs.at("attach-originals", default: "false") == "true"
+#let TABLE_OF_CONTENTS = sys.inputs.at("toc", default: "false") == "true"
+#let PAPER = sys.inputs.at("paper", default: "a4")
+#let LANDSCAPE = sys.inputs.at("landscape", default: "false") == "true"
+// Top, right, bottom and left in mm.
+#let MARGINS = sys.inputs.at("margins", default: "10,10,10,10").split(",").map(float)
+// Main font followed by the fallbacks for missing characters.
+#let FONTS = sys.inputs.at("fonts", default: "Literata").split(",")
+// Header, table and footer size in pt of the fpdf engine, the sizes of this
+// template are scaled relative to their defaults.
+#let FONT_SIZES = sys.inputs.at("font-sizes", default: "10,7,6.8").split(",").map(float)
+#let HEADER_SCALE = FONT_SIZES.at(0) / 10
+#let TABLE_SCALE = FONT_SIZES.at(1) / 7
+#let FOOTER_SCALE = FONT_SIZES.at(2) / 6.8
+#let HEADER_SIZE = 21pt * HEADER_SCALE
 
 // ========================================
 // DATA
 // ========================================
 
 #let dossier = json(if DEBUG { "test-dossier.json" } else { DOSSIER_FILE })
+#let info = json(INFO_FILE)
+#let tables = json(TABLES_FILE)
 
 // ========================================
 // METHODS
 // ========================================
 
-#let render_transaction_table(attachment) = table(
+// The header names the account columns according to the file type, e.g.
+// Konto and Kategorie for cash basis accounting.
+#let render_transaction_table(transactions) = {
+  set text(size: 9pt * TABLE_SCALE)
+  let columns = transactions.Header.len()
+  table(
+    columns: (auto, auto, 1fr) + (auto,) * (columns - 3),
+    align: (x, y) => if x < 3 { left } else { right },
+    stroke: none,
+    inset: (x: 1.5mm, y: 1mm),
+    table.header(..transactions.Header.map(header => [*#header*])),
+    table.hline(stroke: GENERAL_STROKE),
+    ..transactions.Rows.enumerate().map(((i, row)) => row.enumerate().map(((x, cell)) => {
+      // The doc number links to the first other document with it.
+      let link_path = transactions.Links.at(i)
+      let cell = if x == 0 and link_path != "" {
+        link(label("doc:" + link_path), text(fill: teal, cell))
+      } else { cell }
+      if transactions.Auxiliary.at(i) { text(fill: gray, cell) } else { cell }
+    })).flatten(),
+  )
+}
 
-)
+// Outline of the first page of a document: the group (if it changed), the
+// document and its doc numbers. The headings are hidden and only show up as
+// bookmarks.
+#let render_bookmarks(attachment, is_new_group) = {
+  let has_group = attachment.OutlineGroup != ""
+  let level = if has_group { 2 } else { 1 }
+  let title = attachment.Path.split("/").last().split(".").slice(0, -1).join(".")
+  place(hide({
+    if has_group and is_new_group {
+      heading(level: 1, attachment.OutlineGroup)
+    }
+    // Target of the links to this document.
+    [#heading(level: level, title)#label("doc:" + attachment.Path)]
+    if attachment.Outline != none {
+      for entry in attachment.Outline {
+        heading(level: level + 1, entry.Title)
+      }
+    }
+  }))
+}
 
-#let render_header(attachment, is_fist_page) = {
+#let render_header(attachment, transactions, is_fist_page, is_new_group) = {
   set par(spacing: 0mm)
   grid(
     columns: (1fr, 17mm),
@@ -29,33 +88,91 @@
       inset: 2.5mm,
       [
         #set par(spacing: HEADER_SPACING)
-        #set text(size: 13pt)
+        #set text(size: 13pt * HEADER_SCALE)
         #if is_fist_page [
-          = #attachment.Path
+          #render_bookmarks(attachment, is_new_group)
+          #let path_heading = heading(level: 1, outlined: false, bookmarked: false, attachment.Path)
+          #if attachment.FileURL != "" {
+            link(attachment.FileURL, path_heading)
+          } else {
+            path_heading
+          }
         ] else [
           #set text(size: HEADER_SIZE)
           *#sym.arrow #attachment.Path*
         ]
 
         #attachment.FileName -- #attachment.Transactions.first().Ident
+        #if is_fist_page and attachment.Related != none and attachment.Related.len() > 0 [
+          #set text(size: 9pt * TABLE_SCALE)
+          *Siehe auch:*
+          #attachment.Related.map(related => link(
+            label("doc:" + related.Path),
+            [#related.Title (#related.Ident)],
+          )).join(", ")
+        ]
       ],
     ),
     grid.cell(
       align: horizon + center,
-      qrcode(attachment.Path, width: 13.5mm, quiet-zone: 0),
+      qrcode(
+        if attachment.QRPayload != "" { attachment.QRPayload } else { attachment.Path },
+        width: 13.5mm,
+        quiet-zone: 0,
+      ),
     ),
   )
   if is_fist_page {
-    render_transaction_table(attachment)
+    render_transaction_table(transactions)
   }
 }
 
+#let render_einvoice(invoice) = {
+  set text(size: 9pt)
+  pad(x: 1.5mm, y: 4mm)[
+    #text(size: 14pt)[*E-Rechnung (#invoice.Syntax)*]
+    #grid(
+      columns: (35mm, 1fr),
+      row-gutter: 2mm,
+      [*Rechnungsnummer*], invoice.Number,
+      [*Rechnungsdatum*], invoice.IssueDate.slice(0, 10),
+      [*Verkäufer*], invoice.Seller,
+      [*Käufer*], invoice.Buyer,
+      [*Nettobetrag*], [#invoice.NetTotal #invoice.Currency],
+      [*MwSt.*], [#invoice.TaxTotal #invoice.Currency],
+      [*Bruttobetrag*], [#invoice.GrossTotal #invoice.Currency],
+      [*Zahlbetrag*], [#invoice.DueAmount #invoice.Currency],
+    )
+    #if invoice.Lines != none {
+      table(
+        columns: (1fr, 30mm, 30mm),
+        align: (left, right, right),
+        stroke: none,
+        table.header([*Position*], [*Menge*], [*Nettobetrag*]),
+        table.hline(stroke: GENERAL_STROKE),
+        ..invoice.Lines.map(line => (
+          line.Name,
+          [#line.Quantity #line.Unit],
+          [#line.NetAmount #invoice.Currency],
+        )).flatten()
+      )
+    }
+  ]
+}
+
 #let render_content(attachment) = {
-  image(attachment.FileUUID)
+  if attachment.IsXMLInvoice {
+    if attachment.EInvoice != none {
+      render_einvoice(attachment.EInvoice)
+    }
+  } else {
+    image(attachment.FileUUID)
+  }
 }
 
 #let render_footer(current_page, total_pages) = {
   set par(leading: 0.5em)
+  set text(size: 11pt * FOOTER_SCALE)
   grid(
     columns: (1fr, 1fr, 1fr),
     align: (left, center, right),
@@ -66,25 +183,39 @@
       #dossier.ZIPCode #dossier.Place\
     ],
     [
-      *#current_page / #total_pages -- Page TODO*\
+      *#current_page / #total_pages -- Page #context counter(page).display()*\
       TODO -- TODO (Transaction date range)
     ],
     [
-      File: #dossier.AccountingFileName\
-      Accounting data as of: TODO\
-      Report was created on: TODO
+      File: #info.Custom.AccountingFile\
+      Accounting data as of: #info.Custom.AccountingDataAsOf\
+      Report was created on: #datetime.today().display("[day].[month].[year repr:last_two]")
     ]
   )
 }
 
 
-#let render_attachment(attachment) = {
+#let attach_original(attachment) = {
+  let mime-type = if attachment.IsXMLInvoice { "text/xml" } else { "application/pdf" }
+  pdf.embed(
+    attachment.Path.split("/").last(),
+    read(attachment.FileUUID, encoding: none),
+    relationship: "source",
+    mime-type: mime-type,
+    description: "Original receipt: " + attachment.Path,
+  )
+}
+
+#let render_attachment(attachment, transactions, is_new_group) = {
+  if ATTACH_ORIGINALS and attachment.IsValidFile {
+    attach_original(attachment)
+  }
   for page in range(attachment.PageCount) {
     grid(
       columns: 1fr,
       rows: (auto, 1fr, auto),
       stroke: GENERAL_STROKE,
-      render_header(attachment, page == 0),
+      render_header(attachment, transactions, page == 0, is_new_group),
       render_content(attachment),
       render_footer(page + 1, attachment.PageCount),
     )
@@ -95,10 +226,32 @@
 // LAYOUT
 // ========================================
 
+// Custom keys of the document information (source XML hash etc.) can't be
+// set from typst.
+#set document(
+  title: info.Title,
+  author: info.Author,
+  description: info.Subject,
+  keywords: info.Keywords.split(", "),
+)
+
+// The numbering only provides the PDF page labels, the number is part of
+// the footer of each page.
 #set page(
-  margin: 10mm,
+  paper: PAPER,
+  flipped: LANDSCAPE,
+  margin: (
+    top: MARGINS.at(0) * 1mm,
+    right: MARGINS.at(1) * 1mm,
+    bottom: MARGINS.at(2) * 1mm,
+    left: MARGINS.at(3) * 1mm,
+  ),
+  numbering: "1",
+  footer: none,
 )
 
+#set text(font: FONTS)
+
 #show heading.where(level: 1): set block(below: HEADER_SPACING)
 #show heading.where(level: 1): set text(size: HEADER_SIZE)
 
@@ -106,6 +259,13 @@
 // CONTENT
 // ========================================
 
-#for attachment in dossier.JournalEntries {
-  render_attachment(attachment)
+#if TABLE_OF_CONTENTS [
+  #set page(numbering: "i")
+  #outline(title: [Contents])
+]
+#counter(page).update(1)
+
+#for (i, attachment) in dossier.JournalEntries.enumerate() {
+  let is_new_group = i == 0 or dossier.JournalEntries.at(i - 1).OutlineGroup != attachment.OutlineGroup
+  render_attachment(attachment, tables.at(i), is_new_group)
 }
@@ -3,23 +3,37 @@ package main
 import (
 	"bytes"
 	"embed"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path"
+	"strings"
 )
 
 //go:embed static/*
 var staticFiles embed.FS
 
+// Options of the typst engine.
+type TypstOptions struct {
+	DebugMode bool
+	// Embed the original receipt files as file attachments.
+	AttachOriginals bool
+	PDFA            bool
+	// Start with a table of contents.
+	TableOfContents bool
+	Page            PageSetup
+	Fonts           Fonts
+}
+
 type Typst struct {
 	dossier    *Dossier
 	tempDir    string
 	outputFile string
-	debugMode  bool
+	options    TypstOptions
 }
 
-func NewTypst(dossier *Dossier, outputFile string, debugMode bool) (*Typst, error) {
+func NewTypst(dossier *Dossier, outputFile string, options TypstOptions) (*Typst, error) {
 	tempDir, err := os.MkdirTemp("", "typst-*")
 	if err != nil {
 		return nil, err
@@ -28,7 +42,7 @@ func NewTypst(dossier *Dossier, outputFile string, debugMode bool) (*Typst, erro
 		dossier:    dossier,
 		tempDir:    tempDir,
 		outputFile: outputFile,
-		debugMode:  debugMode,
+		options:    options,
 	}, nil
 }
 
@@ -40,6 +54,12 @@ func (t *Typst) Build(debugTempDir bool) error {
 	if err := t.dossier.ToJSON(path.Join(t.tempDir, "dossier.json")); err != nil {
 		return err
 	}
+	if err := t.dossier.DocumentInfo().ToJSON(path.Join(t.tempDir, "document-info.json")); err != nil {
+		return err
+	}
+	if err := t.writeTransactionTables(path.Join(t.tempDir, "tables.json")); err != nil {
+		return err
+	}
 	if debugTempDir {
 		if err := t.debugTempDir(); err != nil {
 			return err
@@ -51,6 +71,71 @@ func (t *Typst) Build(debugTempDir bool) error {
 	return nil
 }
 
+// Transaction table of a document, the cells are formatted like in the fpdf
+// engine.
+type TypstTable struct {
+	Header []string
+	Rows   [][]string
+	// Whether the row is an AP/AR auxiliary booking, shown in gray.
+	Auxiliary []bool
+	// Path of the first other document with the doc number of the row, the
+	// doc number links to it. Empty if there is none.
+	Links []string
+}
+
+// Writes the transaction tables of all documents in the order of the
+// documents. The account columns depend on the file type, which includes
+// forced cash basis accounting.
+func (t *Typst) writeTransactionTables(path string) error {
+	rsl := []TypstTable{}
+	for _, doc := range t.dossier.JournalEntries {
+		rsl = append(rsl, transactionTable(doc, t.dossier.FileType, t.dossier.Currency()))
+	}
+	data, err := json.MarshalIndent(rsl, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func transactionTable(doc Document, fileType FileType, base Currency) TypstTable {
+	cashBasisAccounting := fileType.IsCashBasis()
+	debitHeader, creditHeader := fileType.AccountHeaders()
+	rsl := TypstTable{
+		Header:    []string{"Beleg", "Datum", "Beschreibung", debitHeader, creditHeader},
+		Rows:      [][]string{},
+		Auxiliary: []bool{},
+		Links:     []string{},
+	}
+	if fileType.HasVAT() {
+		rsl.Header = append(rsl.Header, "MwSt.")
+	}
+	rsl.Header = append(rsl.Header, "Betrag")
+	for _, tx := range doc.Transactions {
+		isAuxiliary := tx.IsAPARAuxiliary(cashBasisAccounting)
+		debit, credit := fileType.Accounts(tx)
+		if isAuxiliary {
+			debit, credit = fmt.Sprintf("%s (KS 3)", tx.Cc3), ""
+		}
+		row := []string{tx.Ident, tx.FmtDate(), tx.FmtDescription(), debit, credit}
+		if fileType.HasVAT() {
+			row = append(row, tx.VatCode)
+		}
+		amount := fmt.Sprint(tx.GetAmount(cashBasisAccounting, base), " ", base.Symbol)
+		if tx.IsForeignCurrency(base) {
+			amount = fmt.Sprintf("%s\n%s %s", amount, tx.AmountCurrency, tx.CurrencyCode(base))
+		}
+		rsl.Rows = append(rsl.Rows, append(row, amount))
+		rsl.Auxiliary = append(rsl.Auxiliary, isAuxiliary)
+		link := ""
+		if related := doc.RelatedByIdent(tx.Ident); len(related) != 0 {
+			link = related[0].Path
+		}
+		rsl.Links = append(rsl.Links, link)
+	}
+	return rsl
+}
+
 func (t *Typst) initTempDir() error {
 	for _, doc := range t.dossier.JournalEntries {
 		// Only link if original file is valid and uuid is present
@@ -61,9 +146,28 @@ func (t *Typst) initTempDir() error {
 			}
 		}
 	}
+	if err := t.writeFonts(); err != nil {
+		return err
+	}
 	return writeDirToTarget(staticFiles, "static", t.tempDir)
 }
 
+// Writes the configured fonts into the fonts folder of the temp dir which is
+// passed to typst as font path.
+func (t *Typst) writeFonts() error {
+	dir := path.Join(t.tempDir, "fonts")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	fonts := append([]*Font{t.options.Fonts.Regular, t.options.Fonts.Italic, t.options.Fonts.Bold}, t.options.Fonts.Fallbacks...)
+	for _, font := range fonts {
+		if err := os.WriteFile(path.Join(dir, font.FileName), font.Data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // writeDirToTarget recursively copies contents of embeddedDir in fsys to targetDir.
 func writeDirToTarget(fsys embed.FS, embeddedDir, targetDir string) error {
 	entries, err := fsys.ReadDir(embeddedDir)
@@ -101,13 +205,28 @@ func (t *Typst) buildTemplate() error {
 		return fmt.Errorf("failed to change working directory to temp dir: %w", err)
 	}
 
-	cmd := exec.Command(
-		"typst", "compile",
+	args := []string{
+		"compile",
 		"--input", "input=dossier.json",
-		"--input", fmt.Sprintf("debug=%t", t.debugMode),
-		"template.typ",
-		t.outputFile,
-	)
+		"--input", "info=document-info.json",
+		"--input", "tables=tables.json",
+		"--input", fmt.Sprintf("debug=%t", t.options.DebugMode),
+		"--input", fmt.Sprintf("attach-originals=%t", t.options.AttachOriginals),
+		"--input", fmt.Sprintf("toc=%t", t.options.TableOfContents),
+		"--input", fmt.Sprint("paper=", PAGE_SIZES[t.options.Page.Size]),
+		"--input", fmt.Sprintf("landscape=%t", t.options.Page.Landscape),
+		"--input", fmt.Sprint("margins=", t.options.Page.Margins),
+		"--font-path", "fonts",
+		"--input", fmt.Sprint("fonts=", strings.Join(t.options.Fonts.Families(), ",")),
+		"--input", fmt.Sprintf(
+			"font-sizes=%g,%g,%g",
+			t.options.Fonts.Sizes.Header, t.options.Fonts.Sizes.Table, t.options.Fonts.Sizes.Footer,
+		),
+	}
+	if t.options.PDFA {
+		args = append(args, "--pdf-standard", "a-3b")
+	}
+	cmd := exec.Command("typst", append(args, "template.typ", t.outputFile)...)
 	cmd.Dir = t.tempDir
 
 	// Capture stdout & stderr to show on error
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+)
+
+// Separators used to group thousands on receipts. Includes the Swiss
+// apostrophe variants as well as (narrow) no-break spaces.
+var thousandSeparators = []string{"", "'", "’", ",", ".", " ", "\u00a0", "\u202f"}
+
+// Result of comparing the booked transactions of a document with the
+// text of the linked receipt.
+type Verification struct {
+	// False if the receipt has no (readable) text layer, e.g. a scan.
+	HasText bool
+	// Unique ids of the transactions whose amount wasn't found.
+	MissingAmounts []string
+	// Unique ids of the transactions whose booking date wasn't found.
+	MissingDates []string
+	Error        string
+}
+
+// Whether the receipt couldn't be checked at all.
+func (v *Verification) IsUnverifiable() bool {
+	return v != nil && (v.Error != "" || !v.HasText)
+}
+
+func (v *Verification) IsAmountMissing(tx Transaction) bool {
+	if v == nil {
+		return false
+	}
+	for _, unique := range v.MissingAmounts {
+		if unique == tx.Unique {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Verification) IsDateMissing(tx Transaction) bool {
+	if v == nil {
+		return false
+	}
+	for _, unique := range v.MissingDates {
+		if unique == tx.Unique {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the verification found something the user should look at.
+func (v *Verification) HasIssues() bool {
+	return v != nil && (v.IsUnverifiable() || len(v.MissingAmounts) != 0)
+}
+
+// Verify searches the text of each receipt for the booked amounts and dates.
+// Results are stored in Document.Verification.
+func (d *Dossier) Verify() {
+	for i := range d.JournalEntries {
+		doc := &d.JournalEntries[i]
+		if !doc.IsValidFile {
+			continue
+		}
+		doc.Verification = VerifyDocument(*doc)
+	}
+}
+
+// Returns all documents for which the verification found an issue.
+func (d Dossier) VerificationIssues() Documents {
+	rsl := Documents{}
+	for _, doc := range d.JournalEntries {
+		if doc.Verification.HasIssues() {
+			rsl = append(rsl, doc)
+		}
+	}
+	return rsl
+}
+
+func VerifyDocument(doc Document) *Verification {
+	rsl := &Verification{}
+	text, err := documentText(doc)
+	if err != nil {
+		rsl.Error = err.Error()
+		return rsl
+	}
+	text = removeExtraSpaces(text)
+	rsl.HasText = strings.ContainsAny(text, "0123456789")
+	if !rsl.HasText {
+		return rsl
+	}
+	compact := strings.Join(strings.Fields(text), "")
+
+	for _, tx := range doc.Transactions {
+		found := false
+		for _, amount := range tx.verifiableAmounts() {
+			if containsAnyNumber(text, amountVariants(amount)) ||
+				containsAnyNumber(compact, amountVariants(amount)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			rsl.MissingAmounts = append(rsl.MissingAmounts, tx.Unique)
+		}
+		if !containsAnyNumber(text, dateVariants(tx)) && !containsAnyNumber(compact, dateVariants(tx)) {
+			rsl.MissingDates = append(rsl.MissingDates, tx.Unique)
+		}
+	}
+	return rsl
+}
+
+// Amounts of a transaction which may appear on the receipt. The currency
+// amount comes first as receipts usually state the original amount.
+func (t Transaction) verifiableAmounts() []string {
+	rsl := []string{}
+	for _, amount := range []string{t.AmountCurrency, t.Amount, t.Income, t.Expenses} {
+		amount = strings.TrimSpace(amount)
+		if amount != "" && !slices.Contains(rsl, amount) {
+			rsl = append(rsl, amount)
+		}
+	}
+	return rsl
+}
+
+// Returns the notations in which the given amount may be written on a
+// receipt (e.g. 1234.50, 1'234.50, 1.234,50, 1 234,50).
+func amountVariants(amount string) []string {
+	value, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+	if err != nil {
+		return nil
+	}
+	formatted := strconv.FormatFloat(math.Abs(value), 'f', 2, 64)
+	intPart, decPart, _ := strings.Cut(formatted, ".")
+
+	rsl := []string{}
+	for _, thousandSep := range thousandSeparators {
+		grouped := groupThousands(intPart, thousandSep)
+		for _, decimalSep := range []string{".", ","} {
+			if thousandSep == decimalSep {
+				continue
+			}
+			rsl = append(rsl, grouped+decimalSep+decPart)
+			if decPart == "00" {
+				rsl = append(rsl, grouped+decimalSep+"–", grouped+decimalSep+"-")
+			}
+		}
+	}
+	return rsl
+}
+
+func groupThousands(digits, sep string) string {
+	if sep == "" || len(digits) <= 3 {
+		return digits
+	}
+	var builder strings.Builder
+	head := len(digits) % 3
+	if head != 0 {
+		builder.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if builder.Len() != 0 {
+			builder.WriteString(sep)
+		}
+		builder.WriteString(digits[i : i+3])
+	}
+	return builder.String()
+}
+
+// Returns the common notations of the booking date.
+func dateVariants(tx Transaction) []string {
+	date, err := tx.ParsedDate()
+	if err != nil {
+		return nil
+	}
+	return []string{
+		date.Format("02.01.2006"),
+		date.Format("2.1.2006"),
+		date.Format("02.01.06"),
+		date.Format("2006-01-02"),
+		date.Format("02/01/2006"),
+		date.Format("01/02/2006"),
+		date.Format("02-01-2006"),
+	}
+}
+
+// Reports whether one of the needles occurs in text without being part
+// of a larger number (5.00 must not match 15.00).
+func containsAnyNumber(text string, needles []string) bool {
+	for _, needle := range needles {
+		start := 0
+		for {
+			idx := strings.Index(text[start:], needle)
+			if idx == -1 {
+				break
+			}
+			idx += start
+			end := idx + len(needle)
+			if !isDigitAt(text, idx-1) && !isDigitAt(text, end) {
+				return true
+			}
+			start = idx + 1
+		}
+	}
+	return false
+}
+
+func isDigitAt(text string, idx int) bool {
+	return idx >= 0 && idx < len(text) && text[idx] >= '0' && text[idx] <= '9'
+}
+
+// Text of the receipt. Bare XML invoices are searched as they are.
+func documentText(doc Document) (string, error) {
+	if doc.IsXMLInvoice {
+		data, err := os.ReadFile(doc.AbsolutePath)
+		return string(data), err
+	}
+	return ExtractPDFText(doc.AbsolutePath)
+}
+
+// ExtractPDFText returns the text shown by the content streams of all pages
+// of the PDF. Strings are decoded as PDFDocEncoding, glyphs of embedded
+// CID fonts without a simple encoding are therefore not readable.
+func ExtractPDFText(path string) (string, error) {
+	ctx, err := api.ReadContextFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read PDF: %w", err)
+	}
+	var builder strings.Builder
+	for page := 1; page <= ctx.PageCount; page++ {
+		content, err := pdfcpu.ExtractPageContent(ctx, page)
+		if err != nil {
+			return "", fmt.Errorf("failed to extract content of page %d: %w", page, err)
+		}
+		raw, err := io.ReadAll(content)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(textFromContentStream(raw))
+		builder.WriteString("\n")
+	}
+	return builder.String(), nil
+}
+
+// Collects the literal and hex strings of a content stream. Strings within
+// one TJ array are joined without a separator.
+func textFromContentStream(content []byte) string {
+	var builder strings.Builder
+	inArray := false
+	for i := 0; i < len(content); i++ {
+		switch content[i] {
+		case '[':
+			inArray = true
+		case ']':
+			inArray = false
+			builder.WriteByte(' ')
+		case '(':
+			str, end := readLiteralString(content, i)
+			builder.WriteString(str)
+			if !inArray {
+				builder.WriteByte(' ')
+			}
+			i = end
+		case '<':
+			if i+1 < len(content) && content[i+1] == '<' {
+				i++
+				continue
+			}
+			end := bytes.IndexByte(content[i:], '>')
+			if end == -1 {
+				return builder.String()
+			}
+			builder.WriteString(decodeHexString(content[i+1 : i+end]))
+			if !inArray {
+				builder.WriteByte(' ')
+			}
+			i += end
+		}
+	}
+	return builder.String()
+}
+
+// Reads the literal string starting at the opening parenthesis at start.
+// Returns the decoded string and the index of the closing parenthesis.
+func readLiteralString(content []byte, start int) (string, int) {
+	var builder strings.Builder
+	depth := 0
+	for i := start; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\\' && i+1 < len(content):
+			i++
+			switch next := content[i]; next {
+			case 'n', 'r', 't', 'b', 'f':
+				builder.WriteByte(' ')
+			case '\r', '\n':
+				// Line continuation.
+			default:
+				if next >= '0' && next <= '7' {
+					value := 0
+					j := i
+					for ; j < len(content) && j < i+3 && content[j] >= '0' && content[j] <= '7'; j++ {
+						value = value*8 + int(content[j]-'0')
+					}
+					builder.WriteRune(rune(value & 0xff))
+					i = j - 1
+				} else {
+					builder.WriteRune(rune(next))
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				builder.WriteByte(c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return builder.String(), i
+			}
+			builder.WriteByte(c)
+		default:
+			builder.WriteRune(rune(c))
+		}
+	}
+	return builder.String(), len(content)
+}
+
+// Decodes a hex string. Two byte sequences are interpreted as UTF-16BE if
+// they look like it, everything else as single byte characters.
+func decodeHexString(raw []byte) string {
+	cleaned := strings.Join(strings.Fields(string(raw)), "")
+	if len(cleaned)%2 != 0 {
+		cleaned += "0"
+	}
+	data, err := hex.DecodeString(cleaned)
+	if err != nil {
+		return ""
+	}
+	if len(data) >= 2 && len(data)%2 == 0 && data[0] == 0 {
+		runes := make([]rune, 0, len(data)/2)
+		for i := 0; i < len(data); i += 2 {
+			runes = append(runes, rune(data[i])<<8|rune(data[i+1]))
+		}
+		return string(runes)
+	}
+	runes := make([]rune, 0, len(data))
+	for _, b := range data {
+		runes = append(runes, rune(b))
+	}
+	return string(runes)
+}
+
+// Human readable description of the issues found for the given document.
+func (v *Verification) Messages(doc Document) []string {
+	if v == nil {
+		return nil
+	}
+	if v.Error != "" {
+		return []string{fmt.Sprintf("Receipt couldn't be read: %s", v.Error)}
+	}
+	if !v.HasText {
+		return []string{"Receipt has no text layer (scan?), amounts couldn't be verified"}
+	}
+	rsl := []string{}
+	for _, tx := range doc.Transactions {
+		if !v.IsAmountMissing(tx) {
+			continue
+		}
+		msg := fmt.Sprintf("%s (%s): amount %s not found", tx.Ident, tx.FmtDate(), strings.Join(tx.verifiableAmounts(), " / "))
+		if v.IsDateMissing(tx) {
+			msg += ", booking date not found either"
+		}
+		rsl = append(rsl, msg)
+	}
+	return rsl
+}
+
+// Whether Verify was run on the dossier.
+func (d Dossier) IsVerified() bool {
+	for _, doc := range d.JournalEntries {
+		if doc.Verification != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Prints all verification issues to stdout.
+func (d Dossier) PrintVerificationIssues() {
+	for _, doc := range d.VerificationIssues() {
+		for _, msg := range doc.Verification.Messages(doc) {
+			fmt.Printf("%s: %s\n", doc.Path, msg)
+		}
+	}
+}
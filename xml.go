@@ -1,16 +1,23 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"os"
+	"reflect"
+	"slices"
+	"strings"
 	"time"
 )
 
 const UNKNOWN_STR = "<ERROR>"
 
+// Tables of the export used by the report.
+var AC2_TABLES = []string{"FileInfo", "Journal", "Accounts", "Categories", "ExchangeRates"}
+
 type AC2 struct {
 	XMLName            xml.Name           `xml:"AC2"`
 	Version            string             `xml:"version,attr"`
@@ -19,24 +26,80 @@ type AC2 struct {
 	Tables             []Table            `xml:"Table"`
 }
 
-func AC2FromFile(path string) (*AC2, error) {
+// AC2FromFile reads the given tables of a Banana XML export, see
+// AC2FromReader.
+func AC2FromFile(path string, tables ...string) (*AC2, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
-	raw, err := io.ReadAll(file)
-	if err != nil {
-		return nil, err
+	return AC2FromReader(bufio.NewReader(file), tables...)
+}
+
+// AC2FromReader decodes the export as a stream and only materialises the
+// tables with the given IDs (all if none are given), the rows of the other
+// tables and the styles are skipped. This keeps the memory use of large
+// multi-year exports down to the tables actually used.
+func AC2FromReader(r io.Reader, tables ...string) (*AC2, error) {
+	decoder := xml.NewDecoder(r)
+	rsl := AC2{}
+	isRoot := true
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if isRoot {
+			if start.Name.Local != "AC2" {
+				return nil, fmt.Errorf("expected element 'AC2' but found '%s'", start.Name.Local)
+			}
+			rsl.XMLName = start.Name
+			rsl.Version = attrValue(start, "version")
+			isRoot = false
+			continue
+		}
+		switch start.Name.Local {
+		case "DocumentProperties":
+			err = decoder.DecodeElement(&rsl.DocumentProperties, &start)
+		case "Table":
+			if len(tables) != 0 && !slices.Contains(tables, attrValue(start, "ID")) {
+				err = decoder.Skip()
+				break
+			}
+			var table Table
+			if err = decoder.DecodeElement(&table, &start); err == nil {
+				rsl.Tables = append(rsl.Tables, table)
+			}
+		default:
+			err = decoder.Skip()
+		}
+		if err != nil {
+			return nil, err
+		}
 	}
-	var rsl AC2
-	err = xml.Unmarshal(raw, &rsl)
-	if err != nil {
-		return nil, err
+	if isRoot {
+		return nil, fmt.Errorf("no 'AC2' element found")
 	}
 	return &rsl, nil
 }
 
+func attrValue(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
 func (a AC2) ToJSON(path string) error {
 	data, err := json.MarshalIndent(a, "", "  ")
 	if err != nil {
@@ -132,6 +195,36 @@ func (t Table) TimeById(id string) (time.Time, error) {
 	return time.Parse("15:04:05", value)
 }
 
+// Whether the table has a column with the given ID.
+func (t Table) HasField(id string) bool {
+	_, ok := t.Field(id)
+	return ok
+}
+
+// Column with the given ID.
+func (t Table) Field(id string) (Field, bool) {
+	for _, field := range t.FieldList {
+		if field.ID == id {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+// Whether the table holds cash basis (EÜR) bookings, based on the columns
+// or, for exports without field list, on the rows.
+func (t Table) IsCashBasis() bool {
+	if len(t.FieldList) != 0 {
+		return t.HasField("Income") || t.HasField("Expenses") || t.HasField("Category")
+	}
+	for _, row := range t.RowList {
+		if row.IsCashBasis() {
+			return true
+		}
+	}
+	return false
+}
+
 type Field struct {
 	ID              string `xml:"ID,attr"`
 	Sequence        string `xml:"Sequence"`
@@ -164,9 +257,17 @@ type Row struct {
 	ExchangeCurrency           string `xml:"ExchangeCurrency,omitempty"`
 	ExchangeRate               string `xml:"ExchangeRate,omitempty"`
 	ExchangeMultiplier         string `xml:"ExchangeMultiplier,omitempty"`
+	CurrencyReference          string `xml:"CurrencyReference,omitempty"`
+	Rate                       string `xml:"Rate,omitempty"`
+	Multiplier                 string `xml:"Multiplier,omitempty"`
 	Amount                     string `xml:"Amount,omitempty"`
+	VatCode                    string `xml:"VatCode,omitempty"`
 	Balance                    string `xml:"Balance,omitempty"`
 	Currency                   string `xml:"Currency,omitempty"`
+	Group                      string `xml:"Group,omitempty"`
+	Gr                         string `xml:"Gr,omitempty"`
+	BClass                     string `xml:"BClass,omitempty"`
+	Opening                    string `xml:"Opening,omitempty"`
 	OpeningCurrency            string `xml:"OpeningCurrency,omitempty"`
 	BalanceCalculatedCurrency2 string `xml:"BalanceCalculatedCurrency2,omitempty"`
 	Doc                        string `xml:"Doc,omitempty"`
@@ -181,8 +282,76 @@ type Row struct {
 	Income      string `xml:"Income,omitempty"`
 	Expenses    string `xml:"Expenses,omitempty"`
 	Account     string `xml:"Account,omitempty"`
+	AccountDes  string `xml:"AccountDes,omitempty"`
 	Category    string `xml:"Category,omitempty"`
 	CategoryDes string `xml:"CategoryDes,omitempty"`
+
+	// Values of all columns by field ID, including the ones without a
+	// field above (custom columns, Notes, ExternalReference, ...).
+	Values map[string]string `xml:"-"`
+}
+
+// Fields of Row by the element name of their xml tag.
+var ROW_FIELDS = rowFields()
+
+func rowFields() map[string]int {
+	rsl := map[string]int{}
+	rowType := reflect.TypeOf(Row{})
+	for i := 0; i < rowType.NumField(); i++ {
+		name, options, _ := strings.Cut(rowType.Field(i).Tag.Get("xml"), ",")
+		if name == "" || name == "-" || strings.Contains(options, "attr") {
+			continue
+		}
+		rsl[name] = i
+	}
+	return rsl
+}
+
+// UnmarshalXML reads all columns of the row into Values and sets the fields
+// of the known columns from there.
+func (r *Row) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	*r = Row{ID: attrValue(start, "ID"), Values: map[string]string{}}
+	value := reflect.ValueOf(r).Elem()
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			cell, err := cellValue(decoder)
+			if err != nil {
+				return err
+			}
+			r.Values[token.Name.Local] = cell
+			if i, ok := ROW_FIELDS[token.Name.Local]; ok {
+				value.Field(i).SetString(cell)
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// Text of the element just started, nested elements are skipped.
+func cellValue(decoder *xml.Decoder) (string, error) {
+	rsl := ""
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		switch token := token.(type) {
+		case xml.CharData:
+			rsl += string(token)
+		case xml.StartElement:
+			if err := decoder.Skip(); err != nil {
+				return "", err
+			}
+		case xml.EndElement:
+			return rsl, nil
+		}
+	}
 }
 
 func (r Row) IsCashBasis() bool {
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// Export with the given number of journal rows and as many rows in a table
+// which isn't read.
+func generateAC2(rows int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n<AC2 version=\"1.0\">\n")
+	buf.WriteString(`<Table ID="FileInfo"><RowList>`)
+	buf.WriteString(`<Row ID="1"><IdXml>BasicCurrency</IdXml><Value>CHF</Value></Row>`)
+	buf.WriteString("</RowList></Table>\n")
+	for _, id := range []string{"Journal", "Budget"} {
+		fmt.Fprintf(&buf, `<Table ID="%s"><FieldList>`, id)
+		for _, field := range []string{"Date", "Doc", "DocLink", "Description", "AccountDebit", "AccountCredit", "Amount", "Notes"} {
+			fmt.Fprintf(&buf, `<Field ID="%[1]s"><Name>%[1]s</Name><Datatype>Text</Datatype></Field>`, field)
+		}
+		buf.WriteString("</FieldList><RowList>\n")
+		for i := 1; i <= rows; i++ {
+			fmt.Fprintf(
+				&buf,
+				"<Row ID=\"%[1]d\"><Unique>%[1]d</Unique><Date>2023-%02[2]d-%02[3]d</Date><Doc>%[1]d</Doc><DocLink>receipts/%[1]d.pdf</DocLink><Description>Booking %[1]d</Description><AccountDebit>6000</AccountDebit><AccountCredit>1020</AccountCredit><Amount>%[1]d.50</Amount><Notes>Note of booking %[1]d</Notes></Row>\n",
+				i, i%12+1, i%28+1,
+			)
+		}
+		buf.WriteString("</RowList></Table>\n")
+	}
+	buf.WriteString("</AC2>\n")
+	return buf.Bytes()
+}
+
+func BenchmarkAC2FromReader(b *testing.B) {
+	data := generateAC2(200_000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ac, err := AC2FromReader(bytes.NewReader(data), AC2_TABLES...)
+		if err != nil {
+			b.Fatal(err)
+		}
+		journal, err := ac.TableById("Journal")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(journal.RowList) != 200_000 {
+			b.Fatalf("read %d journal rows", len(journal.RowList))
+		}
+	}
+}